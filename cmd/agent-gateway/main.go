@@ -3,13 +3,16 @@ package main
 import (
 	"flag"
 	"log"
+	"os"
 
 	"github.com/jhaveripatric/agent-gateway/internal/config"
 	"github.com/jhaveripatric/agent-gateway/internal/server"
+	"gopkg.in/yaml.v3"
 )
 
 func main() {
 	configPath := flag.String("config", "config.yaml", "path to config file")
+	printConfig := flag.Bool("print-config", false, "print the resolved config with secrets redacted and exit")
 	flag.Parse()
 
 	cfg, err := config.Load(*configPath)
@@ -17,6 +20,15 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	if *printConfig {
+		out, err := yaml.Marshal(cfg.Redacted())
+		if err != nil {
+			log.Fatalf("Failed to print config: %v", err)
+		}
+		os.Stdout.Write(out)
+		return
+	}
+
 	log.Printf("Loaded config: %s v%s", cfg.Name, cfg.Version)
 
 	srv, err := server.New(cfg)
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// APIKeyStore validates long-lived API keys stored as SHA-256 hashes.
+type APIKeyStore struct {
+	header     string
+	queryParam string
+
+	mu    sync.RWMutex
+	keys  map[string]*APIKey // hex sha256 -> key
+	usage map[string]*keyUsage
+}
+
+// APIKey is a stored key entry. The plaintext key is never kept.
+type APIKey struct {
+	ID        string    `yaml:"id"`
+	Hash      string    `yaml:"hash"` // "sha256:<hex>"
+	Owner     string    `yaml:"owner"`
+	Roles     []string  `yaml:"roles"`
+	Tenant    string    `yaml:"tenant"`     // binds the key to one tenant
+	ExpiresAt time.Time `yaml:"expires_at"` // zero means no expiry
+}
+
+// APIKeyUsage reports how often a key has been used.
+type APIKeyUsage struct {
+	ID        string     `json:"id"`
+	Owner     string     `json:"owner"`
+	Roles     []string   `json:"roles"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Uses      int64      `json:"uses"`
+	LastUsed  *time.Time `json:"last_used,omitempty"`
+}
+
+type keyUsage struct {
+	uses     int64
+	lastUsed time.Time
+}
+
+// NewAPIKeyStore creates an empty store reading keys from the given header
+// or query parameter. Either may be empty to disable that source.
+func NewAPIKeyStore(header, queryParam string) *APIKeyStore {
+	return &APIKeyStore{
+		header:     header,
+		queryParam: queryParam,
+		keys:       make(map[string]*APIKey),
+		usage:      make(map[string]*keyUsage),
+	}
+}
+
+// Load replaces the stored keys with those in a YAML key file.
+// Usage counters survive reloads for keys that keep their ID.
+func (s *APIKeyStore) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read api key file: %w", err)
+	}
+
+	var file struct {
+		Keys []APIKey `yaml:"keys"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("parse api key file: %w", err)
+	}
+
+	keys := make(map[string]*APIKey, len(file.Keys))
+	ids := make(map[string]bool, len(file.Keys))
+	for i := range file.Keys {
+		k := &file.Keys[i]
+		if k.ID == "" || k.Owner == "" {
+			return fmt.Errorf("api key %d: id and owner are required", i)
+		}
+		if ids[k.ID] {
+			return fmt.Errorf("api key %s: duplicate id", k.ID)
+		}
+		ids[k.ID] = true
+
+		digest, ok := strings.CutPrefix(k.Hash, "sha256:")
+		if !ok || len(digest) != sha256.Size*2 {
+			return fmt.Errorf("api key %s: hash must be sha256:<hex>", k.ID)
+		}
+		keys[strings.ToLower(digest)] = k
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	for id := range ids {
+		if s.usage[id] == nil {
+			s.usage[id] = &keyUsage{}
+		}
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// HasKey reports whether the request carries an API key.
+func (s *APIKeyStore) HasKey(r *http.Request) bool {
+	return s.extract(r) != ""
+}
+
+// Authenticate validates the request's API key and returns claims.
+func (s *APIKeyStore) Authenticate(r *http.Request) (*Claims, error) {
+	key := s.extract(r)
+	if key == "" {
+		return nil, fmt.Errorf("missing api key")
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	digest := hex.EncodeToString(sum[:])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[digest]
+	if !ok {
+		return nil, fmt.Errorf("unknown api key")
+	}
+	if !k.ExpiresAt.IsZero() && time.Now().After(k.ExpiresAt) {
+		return nil, fmt.Errorf("api key %s expired", k.ID)
+	}
+
+	u := s.usage[k.ID]
+	u.uses++
+	u.lastUsed = time.Now()
+
+	claims := &Claims{
+		UserID:   k.Owner,
+		Username: k.Owner,
+		Roles:    k.Roles,
+		Method:   "api_key",
+		Tenant:   k.Tenant,
+	}
+	claims.Subject = k.ID
+	return claims, nil
+}
+
+// Usage returns per-key usage counters, sorted by key ID.
+func (s *APIKeyStore) Usage() []APIKeyUsage {
+	s.mu.RLock()
+	usage := make([]APIKeyUsage, 0, len(s.keys))
+	for _, k := range s.keys {
+		u := APIKeyUsage{
+			ID:    k.ID,
+			Owner: k.Owner,
+			Roles: k.Roles,
+			Uses:  s.usage[k.ID].uses,
+		}
+		if !k.ExpiresAt.IsZero() {
+			expires := k.ExpiresAt
+			u.ExpiresAt = &expires
+		}
+		if last := s.usage[k.ID].lastUsed; !last.IsZero() {
+			u.LastUsed = &last
+		}
+		usage = append(usage, u)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(usage, func(i, j int) bool { return usage[i].ID < usage[j].ID })
+	return usage
+}
+
+func (s *APIKeyStore) extract(r *http.Request) string {
+	if s.header != "" {
+		if key := strings.TrimSpace(r.Header.Get(s.header)); key != "" {
+			return key
+		}
+	}
+	if s.queryParam != "" {
+		return r.URL.Query().Get(s.queryParam)
+	}
+	return ""
+}
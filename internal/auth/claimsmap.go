@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ClaimsMapping names the provider claims that populate Claims.
+type ClaimsMapping struct {
+	UserID   string   // default "sub"
+	Username string   // default "preferred_username", then "email", then the user ID
+	Roles    []string // default ["groups"]; space-separated strings are split
+}
+
+// Map converts raw provider claims to Claims.
+func (m ClaimsMapping) Map(raw map[string]any) (*Claims, error) {
+	userClaim := m.UserID
+	if userClaim == "" {
+		userClaim = "sub"
+	}
+	userID, _ := raw[userClaim].(string)
+	if userID == "" {
+		return nil, fmt.Errorf("missing %s claim", userClaim)
+	}
+
+	var username string
+	for _, name := range []string{m.Username, "preferred_username", "email"} {
+		if name == "" {
+			continue
+		}
+		if username, _ = raw[name].(string); username != "" {
+			break
+		}
+	}
+	if username == "" {
+		username = userID
+	}
+
+	roleClaims := m.Roles
+	if len(roleClaims) == 0 {
+		roleClaims = []string{"groups"}
+	}
+	var roles []string
+	for _, name := range roleClaims {
+		roles = append(roles, stringList(raw[name])...)
+	}
+
+	claims := &Claims{
+		UserID:   userID,
+		Username: username,
+		Roles:    roles,
+	}
+	claims.Subject, _ = raw["sub"].(string)
+	claims.Issuer, _ = raw["iss"].(string)
+	claims.ID, _ = raw["jti"].(string)
+	claims.IssuedAt = numericDate(raw["iat"])
+	claims.ExpiresAt = numericDate(raw["exp"])
+
+	return claims, nil
+}
+
+// stringList accepts a JSON array of strings or a space-separated string.
+func stringList(v any) []string {
+	switch t := v.(type) {
+	case string:
+		return strings.Fields(t)
+	case []any:
+		list := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	case []string:
+		return t
+	}
+	return nil
+}
+
+func numericDate(v any) *jwt.NumericDate {
+	if f, ok := v.(float64); ok {
+		return jwt.NewNumericDate(time.Unix(int64(f), 0))
+	}
+	return nil
+}
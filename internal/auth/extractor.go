@@ -6,22 +6,29 @@ import (
 	"strings"
 )
 
-// ExtractToken extracts Bearer token from Authorization header.
-func ExtractToken(r *http.Request) (string, error) {
+// ExtractToken extracts Bearer token from Authorization header. When
+// cookieName is set and the header is absent, the token is read from that
+// session cookie instead; fromCookie reports which source was used.
+func ExtractToken(r *http.Request, cookieName string) (token string, fromCookie bool, err error) {
 	header := r.Header.Get("Authorization")
 	if header == "" {
-		return "", fmt.Errorf("missing Authorization header")
+		if cookieName != "" {
+			if c, err := r.Cookie(cookieName); err == nil && c.Value != "" {
+				return c.Value, true, nil
+			}
+		}
+		return "", false, fmt.Errorf("missing Authorization header")
 	}
 
 	parts := strings.SplitN(header, " ", 2)
 	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-		return "", fmt.Errorf("invalid Authorization header format")
+		return "", false, fmt.Errorf("invalid Authorization header format")
 	}
 
-	token := strings.TrimSpace(parts[1])
+	token = strings.TrimSpace(parts[1])
 	if token == "" {
-		return "", fmt.Errorf("empty token")
+		return "", false, fmt.Errorf("empty token")
 	}
 
-	return token, nil
+	return token, false, nil
 }
@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IntrospectionConfig configures an RFC 7662 token introspection endpoint.
+type IntrospectionConfig struct {
+	Name         string
+	Endpoint     string
+	ClientID     string
+	ClientSecret string
+	CacheTTL     time.Duration // default 60s; never beyond the token's exp
+	CacheSize    int           // active results kept, default 10000
+	// InactiveCacheSize bounds cached rejections separately, so random
+	// bearer strings cannot push out active tokens. Default 1000.
+	InactiveCacheSize int
+	Claims            ClaimsMapping
+}
+
+// Introspector validates opaque tokens against an introspection endpoint
+// and caches the results. Concurrent lookups of one token share a call.
+type Introspector struct {
+	cfg    IntrospectionConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	active   *lru
+	inactive *lru
+	inflight map[string]*introspectCall
+}
+
+type introspection struct {
+	claims  *Claims // nil when the token is inactive
+	expires time.Time
+}
+
+// introspectCall is an endpoint call that later lookups of the same token
+// wait on.
+type introspectCall struct {
+	done   chan struct{}
+	claims *Claims
+	err    error
+}
+
+// NewIntrospector creates an introspection client.
+func NewIntrospector(cfg IntrospectionConfig, client *http.Client) *Introspector {
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = time.Minute
+	}
+	if cfg.CacheSize == 0 {
+		cfg.CacheSize = 10000
+	}
+	if cfg.InactiveCacheSize == 0 {
+		cfg.InactiveCacheSize = 1000
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Introspector{
+		cfg:      cfg,
+		client:   client,
+		active:   newLRU(cfg.CacheSize),
+		inactive: newLRU(cfg.InactiveCacheSize),
+		inflight: make(map[string]*introspectCall),
+	}
+}
+
+// Introspect returns claims for an active token.
+func (in *Introspector) Introspect(ctx context.Context, token string) (*Claims, error) {
+	cacheKey := hashToken(token)
+
+	in.mu.Lock()
+	if cached, ok := in.lookup(cacheKey); ok {
+		in.mu.Unlock()
+		if cached.claims == nil {
+			return nil, fmt.Errorf("token inactive")
+		}
+		return cached.claims, nil
+	}
+	call, shared := in.inflight[cacheKey]
+	if !shared {
+		call = &introspectCall{done: make(chan struct{})}
+		in.inflight[cacheKey] = call
+	}
+	in.mu.Unlock()
+
+	if !shared {
+		// Waiters depend on the result, so one caller leaving must not
+		// cancel it; the client timeout still bounds the call
+		in.fill(context.WithoutCancel(ctx), cacheKey, token, call)
+	}
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if call.err != nil {
+		return nil, call.err
+	}
+	if call.claims == nil {
+		return nil, fmt.Errorf("token inactive")
+	}
+	return call.claims, nil
+}
+
+// hashToken keys the cache so raw tokens are not kept in memory.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// lookup returns an unexpired cached result. Called with in.mu held.
+func (in *Introspector) lookup(key string) (introspection, bool) {
+	now := time.Now()
+	for _, c := range []*lru{in.active, in.inactive} {
+		if v, ok := c.get(key); ok {
+			if now.Before(v.expires) {
+				return v, true
+			}
+			c.remove(key)
+		}
+	}
+	return introspection{}, false
+}
+
+func (in *Introspector) fill(ctx context.Context, key, token string, call *introspectCall) {
+	now := time.Now()
+	call.claims, call.err = in.introspect(ctx, token)
+
+	in.mu.Lock()
+	delete(in.inflight, key)
+	if call.err == nil {
+		expires := now.Add(in.cfg.CacheTTL)
+		if c := call.claims; c != nil && c.ExpiresAt != nil && c.ExpiresAt.Time.Before(expires) {
+			expires = c.ExpiresAt.Time
+		}
+		result := introspection{claims: call.claims, expires: expires}
+		if call.claims != nil {
+			in.active.add(key, result)
+		} else {
+			in.inactive.add(key, result)
+		}
+	}
+	in.mu.Unlock()
+	close(call.done)
+}
+
+// lru is a size-bounded cache of introspection results, least recently
+// used first out. It is not safe for concurrent use.
+type lru struct {
+	max   int
+	order *list.List // front is most recent; values are *lruEntry
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value introspection
+}
+
+func newLRU(max int) *lru {
+	return &lru{max: max, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *lru) get(key string) (introspection, bool) {
+	el, ok := c.items[key]
+	if !ok {
+		return introspection{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry).value, true
+}
+
+func (c *lru) add(key string, v introspection) {
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruEntry).value = v
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: v})
+	for c.order.Len() > c.max {
+		c.remove(c.order.Back().Value.(*lruEntry).key)
+	}
+}
+
+func (c *lru) remove(key string) {
+	if el, ok := c.items[key]; ok {
+		c.order.Remove(el)
+		delete(c.items, key)
+	}
+}
+
+func (c *lru) len() int {
+	return c.order.Len()
+}
+
+// introspect calls the endpoint. It returns nil claims for inactive tokens.
+func (in *Introspector) introspect(ctx context.Context, token string) (*Claims, error) {
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {"access_token"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, in.cfg.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if in.cfg.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(in.cfg.ClientID), url.QueryEscape(in.cfg.ClientSecret))
+	}
+
+	resp, err := in.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("introspect: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspect: status %d", resp.StatusCode)
+	}
+
+	var raw map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("introspect: decode: %w", err)
+	}
+	if active, _ := raw["active"].(bool); !active {
+		return nil, nil
+	}
+	if exp := numericDate(raw["exp"]); exp != nil && time.Now().After(exp.Time) {
+		return nil, nil
+	}
+
+	// RFC 7662 uses "username" for the human-readable identifier
+	mapping := in.cfg.Claims
+	if mapping.Username == "" {
+		mapping.Username = "username"
+	}
+	claims, err := mapping.Map(raw)
+	if err != nil {
+		return nil, err
+	}
+	claims.Method = "introspection"
+	claims.raw = raw
+	return claims, nil
+}
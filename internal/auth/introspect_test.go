@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testIntrospection is a stand-in RFC 7662 endpoint. Tokens in active
+// are active; everything else is reported inactive.
+type testIntrospection struct {
+	*httptest.Server
+	calls  atomic.Int32
+	status atomic.Int32  // response status, default 200
+	hold   chan struct{} // when set before use, requests wait on it
+
+	mu     sync.Mutex
+	active map[string]map[string]any
+}
+
+func newTestIntrospection(t *testing.T) *testIntrospection {
+	t.Helper()
+	ti := &testIntrospection{active: make(map[string]map[string]any)}
+	ti.status.Store(http.StatusOK)
+	ti.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ti.calls.Add(1)
+		if ti.hold != nil {
+			<-ti.hold
+		}
+		if id, secret, ok := r.BasicAuth(); !ok || id != "gateway" || secret != "s3cret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if status := int(ti.status.Load()); status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		ti.mu.Lock()
+		resp, ok := ti.active[r.PostFormValue("token")]
+		ti.mu.Unlock()
+		if !ok {
+			resp = map[string]any{"active": false}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(ti.Close)
+	return ti
+}
+
+func (ti *testIntrospection) introspector(cfg IntrospectionConfig) *Introspector {
+	cfg.Endpoint = ti.URL
+	cfg.ClientID = "gateway"
+	cfg.ClientSecret = "s3cret"
+	return NewIntrospector(cfg, ti.Client())
+}
+
+func (ti *testIntrospection) activate(token string, claims map[string]any) {
+	claims["active"] = true
+	ti.mu.Lock()
+	ti.active[token] = claims
+	ti.mu.Unlock()
+}
+
+func TestIntrospectActive(t *testing.T) {
+	ti := newTestIntrospection(t)
+	ti.activate("opaque-1", map[string]any{"sub": "u-1", "username": "ada"})
+	in := ti.introspector(IntrospectionConfig{})
+	ctx := context.Background()
+
+	for range 3 {
+		claims, err := in.Introspect(ctx, "opaque-1")
+		if err != nil {
+			t.Fatalf("Introspect: %v", err)
+		}
+		if claims.UserID != "u-1" || claims.Username != "ada" || claims.Method != "introspection" {
+			t.Fatalf("claims = %+v", claims)
+		}
+	}
+	if n := ti.calls.Load(); n != 1 {
+		t.Errorf("endpoint called %d times, want 1", n)
+	}
+}
+
+func TestIntrospectInactive(t *testing.T) {
+	ti := newTestIntrospection(t)
+	ti.activate("expired", map[string]any{"sub": "u-1", "exp": time.Now().Add(-time.Minute).Unix()})
+	in := ti.introspector(IntrospectionConfig{})
+	ctx := context.Background()
+
+	for _, token := range []string{"unknown", "expired", "unknown"} {
+		if _, err := in.Introspect(ctx, token); err == nil {
+			t.Errorf("Introspect(%s) succeeded, want error", token)
+		}
+	}
+	// The repeated unknown token is served from the inactive cache
+	if n := ti.calls.Load(); n != 2 {
+		t.Errorf("endpoint called %d times, want 2", n)
+	}
+}
+
+func TestIntrospectCacheExpiry(t *testing.T) {
+	ti := newTestIntrospection(t)
+	ti.activate("short", map[string]any{"sub": "u-1", "exp": time.Now().Add(time.Second).Unix()})
+	in := ti.introspector(IntrospectionConfig{CacheTTL: time.Hour})
+	ctx := context.Background()
+
+	if _, err := in.Introspect(ctx, "short"); err != nil {
+		t.Fatalf("Introspect: %v", err)
+	}
+	// The result is never cached past the token's exp
+	in.mu.Lock()
+	cached, ok := in.active.get(hashToken("short"))
+	in.mu.Unlock()
+	if !ok || cached.expires.After(time.Now().Add(2*time.Second)) {
+		t.Errorf("cached until %v, want the token's exp", cached.expires)
+	}
+}
+
+func TestIntrospectBounded(t *testing.T) {
+	ti := newTestIntrospection(t)
+	ti.activate("good", map[string]any{"sub": "u-1"})
+	in := ti.introspector(IntrospectionConfig{CacheSize: 2, InactiveCacheSize: 3})
+	ctx := context.Background()
+
+	if _, err := in.Introspect(ctx, "good"); err != nil {
+		t.Fatalf("Introspect: %v", err)
+	}
+	for i := range 50 {
+		in.Introspect(ctx, fmt.Sprintf("random-%d", i))
+	}
+
+	in.mu.Lock()
+	active, inactive := in.active.len(), in.inactive.len()
+	in.mu.Unlock()
+	if active != 1 || inactive != 3 {
+		t.Errorf("cached %d active and %d inactive, want 1 and 3", active, inactive)
+	}
+
+	// Unknown tokens did not push out the active one
+	calls := ti.calls.Load()
+	if _, err := in.Introspect(ctx, "good"); err != nil {
+		t.Fatalf("Introspect: %v", err)
+	}
+	if ti.calls.Load() != calls {
+		t.Error("active token was evicted by inactive ones")
+	}
+}
+
+func TestIntrospectCoalesces(t *testing.T) {
+	ti := newTestIntrospection(t)
+	ti.activate("shared", map[string]any{"sub": "u-1"})
+	ti.hold = make(chan struct{})
+	in := ti.introspector(IntrospectionConfig{})
+
+	const callers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := in.Introspect(context.Background(), "shared")
+			errs <- err
+		}()
+	}
+
+	// Let every caller reach the in-flight call before answering
+	deadline := time.Now().Add(2 * time.Second)
+	for ti.calls.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(ti.hold)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Introspect: %v", err)
+		}
+	}
+	if n := ti.calls.Load(); n != 1 {
+		t.Errorf("endpoint called %d times, want 1", n)
+	}
+}
+
+func TestIntrospectErrorsNotCached(t *testing.T) {
+	ti := newTestIntrospection(t)
+	ti.activate("opaque-1", map[string]any{"sub": "u-1"})
+	ti.status.Store(http.StatusInternalServerError)
+	in := ti.introspector(IntrospectionConfig{})
+	ctx := context.Background()
+
+	if _, err := in.Introspect(ctx, "opaque-1"); err == nil {
+		t.Fatal("Introspect succeeded against a failing endpoint")
+	}
+	ti.status.Store(http.StatusOK)
+	if _, err := in.Introspect(ctx, "opaque-1"); err != nil {
+		t.Fatalf("Introspect after recovery: %v", err)
+	}
+}
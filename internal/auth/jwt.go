@@ -3,9 +3,12 @@ package auth
 import (
 	"crypto/ecdsa"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"os"
+	"sort"
+	"sync"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -15,6 +18,14 @@ type JWTVerifier struct {
 	publicKeys map[string]*ecdsa.PublicKey // kid -> key
 	issuer     string
 	audience   string
+	mu         sync.RWMutex
+}
+
+// KeyInfo describes a loaded verification key.
+type KeyInfo struct {
+	ID        string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Issuer    string `json:"issuer,omitempty"`
 }
 
 // Claims represents JWT claims with user info.
@@ -23,6 +34,39 @@ type Claims struct {
 	Username string   `json:"username"`
 	Roles    []string `json:"roles"`
 	jwt.RegisteredClaims
+
+	// Method records how the caller authenticated (bearer, mtls, ...).
+	Method string `json:"-"`
+
+	// Tenant is the tenant a credential is bound to, for credentials such
+	// as API keys that carry no tenant claim.
+	Tenant string `json:"-"`
+
+	// raw holds every claim in the token, including unmapped ones.
+	raw map[string]any
+}
+
+// UnmarshalJSON decodes the typed claims and keeps the raw claim set so
+// scopes and custom claims remain available.
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	type plain Claims
+	if err := json.Unmarshal(data, (*plain)(c)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &c.raw)
+}
+
+// Scopes returns the OAuth scopes from the "scope" (space-separated) and
+// "scp" (list or string) claims.
+func (c *Claims) Scopes() []string {
+	scopes := stringList(c.raw["scope"])
+	return append(scopes, stringList(c.raw["scp"])...)
+}
+
+// Claim returns a raw claim by name.
+func (c *Claims) Claim(name string) (any, bool) {
+	v, ok := c.raw[name]
+	return v, ok
 }
 
 // NewJWTVerifier creates a new JWT verifier.
@@ -56,15 +100,32 @@ func (v *JWTVerifier) LoadPublicKey(keyID, path string) error {
 		return fmt.Errorf("not an ECDSA public key")
 	}
 
+	v.mu.Lock()
 	v.publicKeys[keyID] = ecdsaPub
+	v.mu.Unlock()
 	return nil
 }
 
 // HasKeys returns true if any public keys are loaded.
 func (v *JWTVerifier) HasKeys() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
 	return len(v.publicKeys) > 0
 }
 
+// Keys returns the loaded key IDs and their algorithms, sorted by ID.
+func (v *JWTVerifier) Keys() []KeyInfo {
+	v.mu.RLock()
+	keys := make([]KeyInfo, 0, len(v.publicKeys))
+	for kid := range v.publicKeys {
+		keys = append(keys, KeyInfo{ID: kid, Algorithm: "ES256", Issuer: v.issuer})
+	}
+	v.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
+}
+
 // Verify validates a JWT and returns claims.
 func (v *JWTVerifier) Verify(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (any, error) {
@@ -80,7 +141,9 @@ func (v *JWTVerifier) Verify(tokenString string) (*Claims, error) {
 		}
 
 		// Lookup public key
+		v.mu.RLock()
 		key, ok := v.publicKeys[kid]
+		v.mu.RUnlock()
 		if !ok {
 			return nil, fmt.Errorf("unknown kid: %s", kid)
 		}
@@ -114,5 +177,6 @@ func (v *JWTVerifier) Verify(tokenString string) (*Claims, error) {
 		return nil, fmt.Errorf("invalid audience")
 	}
 
+	claims.Method = "bearer"
 	return claims, nil
 }
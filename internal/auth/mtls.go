@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// ClientCertClaims builds claims from a verified TLS client certificate.
+// The subject CN becomes the user ID and OUs become roles.
+func ClientCertClaims(state *tls.ConnectionState) (*Claims, error) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, fmt.Errorf("no verified client certificate")
+	}
+
+	cert := state.VerifiedChains[0][0]
+	if cert.Subject.CommonName == "" {
+		return nil, fmt.Errorf("client certificate has no common name")
+	}
+
+	claims := &Claims{
+		UserID:   cert.Subject.CommonName,
+		Username: cert.Subject.CommonName,
+		Roles:    cert.Subject.OrganizationalUnit,
+		Method:   "mtls",
+	}
+	claims.Subject = cert.Subject.String()
+	claims.Issuer = cert.Issuer.String()
+
+	return claims, nil
+}
@@ -0,0 +1,298 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minJWKSRefetch limits how often an unknown kid may trigger a JWKS fetch.
+const minJWKSRefetch = time.Minute
+
+// OIDCConfig configures an external OpenID Connect issuer.
+type OIDCConfig struct {
+	Name         string
+	Issuer       string
+	Audience     string
+	DiscoveryURL string        // default Issuer + "/.well-known/openid-configuration"
+	JWKSRefresh  time.Duration // default 1h
+	Claims       ClaimsMapping
+}
+
+// OIDCProvider verifies JWTs from an external issuer using keys from the
+// JWKS advertised in its discovery document.
+type OIDCProvider struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu        sync.RWMutex
+	jwksURI   string
+	keys      map[string]jwk
+	fetchedAt time.Time
+}
+
+// jwk is a parsed JSON Web Key.
+type jwk struct {
+	alg string
+	key any
+}
+
+// NewOIDCProvider creates a provider. Keys are fetched lazily on first use.
+func NewOIDCProvider(cfg OIDCConfig, client *http.Client) *OIDCProvider {
+	if cfg.DiscoveryURL == "" {
+		cfg.DiscoveryURL = strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	}
+	if cfg.JWKSRefresh == 0 {
+		cfg.JWKSRefresh = time.Hour
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OIDCProvider{cfg: cfg, client: client}
+}
+
+// Issuer returns the issuer this provider verifies.
+func (p *OIDCProvider) Issuer() string {
+	return p.cfg.Issuer
+}
+
+// Refresh fetches the discovery document and JWKS.
+func (p *OIDCProvider) Refresh(ctx context.Context) error {
+	p.mu.RLock()
+	jwksURI := p.jwksURI
+	p.mu.RUnlock()
+
+	if jwksURI == "" {
+		var doc struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := p.getJSON(ctx, p.cfg.DiscoveryURL, &doc); err != nil {
+			return fmt.Errorf("discovery: %w", err)
+		}
+		if doc.Issuer != p.cfg.Issuer {
+			return fmt.Errorf("discovery issuer %q does not match %q", doc.Issuer, p.cfg.Issuer)
+		}
+		if doc.JWKSURI == "" {
+			return fmt.Errorf("discovery document has no jwks_uri")
+		}
+		jwksURI = doc.JWKSURI
+	}
+
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := p.getJSON(ctx, jwksURI, &set); err != nil {
+		return fmt.Errorf("jwks: %w", err)
+	}
+
+	keys := make(map[string]jwk, len(set.Keys))
+	for _, raw := range set.Keys {
+		kid, k, err := parseJWK(raw)
+		if err != nil {
+			// Skip keys we cannot use (e.g. encryption keys) but keep the rest
+			continue
+		}
+		keys[kid] = k
+	}
+
+	p.mu.Lock()
+	p.jwksURI = jwksURI
+	p.keys = keys
+	p.fetchedAt = time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+// Verify validates a JWT issued by this provider and maps its claims.
+func (p *OIDCProvider) Verify(ctx context.Context, tokenString string) (*Claims, error) {
+	raw := jwt.MapClaims{}
+	opts := []jwt.ParserOption{
+		jwt.WithIssuer(p.cfg.Issuer),
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+	}
+	if p.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(p.cfg.Audience))
+	}
+
+	_, err := jwt.ParseWithClaims(tokenString, raw, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		k, err := p.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if k.alg != "" && k.alg != token.Method.Alg() {
+			return nil, fmt.Errorf("algorithm %s does not match key %s", token.Method.Alg(), kid)
+		}
+		return k.key, nil
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("parse token: %w", err)
+	}
+
+	claims, err := p.cfg.Claims.Map(raw)
+	if err != nil {
+		return nil, err
+	}
+	claims.Method = "oidc"
+	claims.raw = raw
+	return claims, nil
+}
+
+// Keys returns the provider's cached key IDs and algorithms.
+func (p *OIDCProvider) Keys() []KeyInfo {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	keys := make([]KeyInfo, 0, len(p.keys))
+	for kid, k := range p.keys {
+		keys = append(keys, KeyInfo{ID: kid, Algorithm: k.alg, Issuer: p.cfg.Issuer})
+	}
+	return keys
+}
+
+// key looks up a signing key, refreshing the JWKS when it is stale or the
+// kid is unknown (key rotation).
+func (p *OIDCProvider) key(ctx context.Context, kid string) (jwk, error) {
+	p.mu.RLock()
+	k, ok := p.keys[kid]
+	age := time.Since(p.fetchedAt)
+	p.mu.RUnlock()
+
+	stale := age > p.cfg.JWKSRefresh
+	if ok && !stale {
+		return k, nil
+	}
+	if ok || age > minJWKSRefetch {
+		if err := p.Refresh(ctx); err != nil && !ok {
+			return jwk{}, err
+		}
+	}
+
+	p.mu.RLock()
+	k, ok = p.keys[kid]
+	p.mu.RUnlock()
+	if !ok {
+		return jwk{}, fmt.Errorf("unknown kid: %s", kid)
+	}
+	return k, nil
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func parseJWK(raw json.RawMessage) (string, jwk, error) {
+	var k struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Alg string `json:"alg"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+	if err := json.Unmarshal(raw, &k); err != nil {
+		return "", jwk{}, err
+	}
+	if k.Use != "" && k.Use != "sig" {
+		return "", jwk{}, fmt.Errorf("key %s is not a signing key", k.Kid)
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return "", jwk{}, fmt.Errorf("decode n: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return "", jwk{}, fmt.Errorf("decode e: %w", err)
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return k.Kid, jwk{alg: k.Alg, key: pub}, nil
+
+	case "EC":
+		pub, alg, err := parseECKey(k.Crv, k.X, k.Y)
+		if err != nil {
+			return "", jwk{}, err
+		}
+		if k.Alg != "" {
+			alg = k.Alg
+		}
+		return k.Kid, jwk{alg: alg, key: pub}, nil
+	}
+
+	return "", jwk{}, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func parseECKey(crv, x, y string) (*ecdsa.PublicKey, string, error) {
+	var curve elliptic.Curve
+	var ecdhCurve ecdh.Curve
+	var alg string
+	switch crv {
+	case "P-256":
+		curve, ecdhCurve, alg = elliptic.P256(), ecdh.P256(), "ES256"
+	case "P-384":
+		curve, ecdhCurve, alg = elliptic.P384(), ecdh.P384(), "ES384"
+	case "P-521":
+		curve, ecdhCurve, alg = elliptic.P521(), ecdh.P521(), "ES512"
+	default:
+		return nil, "", fmt.Errorf("unsupported curve %q", crv)
+	}
+
+	xb, err := base64.RawURLEncoding.DecodeString(x)
+	if err != nil {
+		return nil, "", fmt.Errorf("decode x: %w", err)
+	}
+	yb, err := base64.RawURLEncoding.DecodeString(y)
+	if err != nil {
+		return nil, "", fmt.Errorf("decode y: %w", err)
+	}
+
+	// Reject points that are not on the curve
+	size := (curve.Params().BitSize + 7) / 8
+	if len(xb) != size || len(yb) != size {
+		return nil, "", fmt.Errorf("invalid coordinate length")
+	}
+	point := append([]byte{4}, append(xb, yb...)...)
+	if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+		return nil, "", fmt.Errorf("invalid EC point: %w", err)
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(xb),
+		Y:     new(big.Int).SetBytes(yb),
+	}, alg, nil
+}
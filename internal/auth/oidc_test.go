@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testIdP is a stand-in OpenID provider serving discovery and a JWKS.
+type testIdP struct {
+	*httptest.Server
+	issuer      string // advertised in discovery; defaults to the server URL
+	jwksFetches atomic.Int32
+
+	mu   sync.Mutex
+	keys map[string]*ecdsa.PrivateKey
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	idp := &testIdP{keys: make(map[string]*ecdsa.PrivateKey)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := idp.issuer
+		if issuer == "" {
+			issuer = idp.URL
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer,
+			"jwks_uri": idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.jwksFetches.Add(1)
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+		keys := []map[string]string{
+			// Encryption keys are skipped without failing the set
+			{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		}
+		for kid, k := range idp.keys {
+			keys = append(keys, map[string]string{
+				"kty": "EC",
+				"kid": kid,
+				"use": "sig",
+				"crv": "P-256",
+				"x":   base64.RawURLEncoding.EncodeToString(k.PublicKey.X.FillBytes(make([]byte, 32))),
+				"y":   base64.RawURLEncoding.EncodeToString(k.PublicKey.Y.FillBytes(make([]byte, 32))),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// rotate publishes a new signing key under kid, replacing the others.
+func (idp *testIdP) rotate(t *testing.T, kid string) {
+	t.Helper()
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.mu.Lock()
+	idp.keys = map[string]*ecdsa.PrivateKey{kid: k}
+	idp.mu.Unlock()
+}
+
+func (idp *testIdP) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	idp.mu.Lock()
+	k := idp.keys[kid]
+	idp.mu.Unlock()
+	if k == nil {
+		t.Fatalf("no key %s", kid)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (idp *testIdP) claims(extra jwt.MapClaims) jwt.MapClaims {
+	c := jwt.MapClaims{
+		"iss":                idp.URL,
+		"sub":                "u-123",
+		"aud":                "agent-gateway",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"preferred_username": "ada",
+		"groups":             []string{"admin", "billing"},
+	}
+	for k, v := range extra {
+		c[k] = v
+	}
+	return c
+}
+
+func newTestProvider(idp *testIdP) *OIDCProvider {
+	return NewOIDCProvider(OIDCConfig{
+		Issuer:   idp.URL,
+		Audience: "agent-gateway",
+	}, idp.Client())
+}
+
+func TestOIDCVerify(t *testing.T) {
+	idp := newTestIdP(t)
+	idp.rotate(t, "k1")
+	p := newTestProvider(idp)
+
+	claims, err := p.Verify(context.Background(), idp.sign(t, "k1", idp.claims(nil)))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.UserID != "u-123" || claims.Username != "ada" || claims.Method != "oidc" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if len(claims.Roles) != 2 || claims.Roles[0] != "admin" {
+		t.Errorf("roles = %v, want [admin billing]", claims.Roles)
+	}
+	if keys := p.Keys(); len(keys) != 1 || keys[0].ID != "k1" || keys[0].Algorithm != "ES256" {
+		t.Errorf("keys = %+v", keys)
+	}
+}
+
+func TestOIDCRejects(t *testing.T) {
+	idp := newTestIdP(t)
+	idp.rotate(t, "k1")
+	p := newTestProvider(idp)
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+	}{
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}},
+		{"no expiry", jwt.MapClaims{"exp": nil}},
+		{"wrong audience", jwt.MapClaims{"aud": "someone-else"}},
+		{"wrong issuer", jwt.MapClaims{"iss": "https://evil.example"}},
+		{"no subject", jwt.MapClaims{"sub": nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := idp.claims(nil)
+			for k, v := range tt.claims {
+				if v == nil {
+					delete(c, k)
+				} else {
+					c[k] = v
+				}
+			}
+			if _, err := p.Verify(context.Background(), idp.sign(t, "k1", c)); err == nil {
+				t.Error("Verify succeeded, want error")
+			}
+		})
+	}
+
+	t.Run("unsigned", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodNone, idp.claims(nil))
+		token.Header["kid"] = "k1"
+		s, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		if _, err := p.Verify(context.Background(), s); err == nil {
+			t.Error("Verify accepted alg none")
+		}
+	})
+}
+
+func TestOIDCIssuerMismatch(t *testing.T) {
+	idp := newTestIdP(t)
+	idp.rotate(t, "k1")
+	idp.issuer = "https://other.example"
+	p := newTestProvider(idp)
+
+	if err := p.Refresh(context.Background()); err == nil {
+		t.Fatal("Refresh accepted a discovery document for another issuer")
+	}
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	idp := newTestIdP(t)
+	idp.rotate(t, "k1")
+	p := newTestProvider(idp)
+	ctx := context.Background()
+
+	if _, err := p.Verify(ctx, idp.sign(t, "k1", idp.claims(nil))); err != nil {
+		t.Fatalf("Verify k1: %v", err)
+	}
+
+	// An unknown kid right after a fetch does not hit the IdP again
+	idp.rotate(t, "k2")
+	token := idp.sign(t, "k2", idp.claims(nil))
+	if _, err := p.Verify(ctx, token); err == nil {
+		t.Fatal("Verify k2 succeeded before the refetch interval")
+	}
+	if n := idp.jwksFetches.Load(); n != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1", n)
+	}
+
+	// Once the interval has passed, the unknown kid triggers a refresh
+	p.mu.Lock()
+	p.fetchedAt = time.Now().Add(-2 * minJWKSRefetch)
+	p.mu.Unlock()
+	if _, err := p.Verify(ctx, token); err != nil {
+		t.Fatalf("Verify k2 after rotation: %v", err)
+	}
+	if n := idp.jwksFetches.Load(); n != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", n)
+	}
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// pruneInterval bounds how often revocations sweep expired entries.
+const pruneInterval = time.Minute
+
+// RevocationList is an in-memory deny-list of revoked tokens and users.
+// Entries expire when the tokens they cover would have expired anyway.
+type RevocationList struct {
+	mu     sync.RWMutex
+	tokens map[string]time.Time      // jti -> expiry
+	users  map[string]userRevocation // user ID -> revocation
+	pruned time.Time
+}
+
+type userRevocation struct {
+	issuedBefore time.Time // whole second; tokens issued before it are revoked
+	until        time.Time
+}
+
+// NewRevocationList creates an empty revocation list.
+func NewRevocationList() *RevocationList {
+	return &RevocationList{
+		tokens: make(map[string]time.Time),
+		users:  make(map[string]userRevocation),
+	}
+}
+
+// RevokeToken denies the token with the given jti until it expires.
+func (l *RevocationList) RevokeToken(jti string, expires time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.maybePruneLocked(time.Now())
+	if current, ok := l.tokens[jti]; !ok || expires.After(current) {
+		l.tokens[jti] = expires
+	}
+}
+
+// RevokeUser denies all of a user's tokens issued before issuedBefore. iat
+// has whole-second precision, so tokens issued in the same second as the
+// revocation, such as a re-login right after it, stay valid. The entry is
+// kept until the longest-lived revoked token would have expired.
+func (l *RevocationList) RevokeUser(userID string, issuedBefore, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	issuedBefore = issuedBefore.Truncate(time.Second)
+	l.maybePruneLocked(time.Now())
+	current, ok := l.users[userID]
+	if ok && current.issuedBefore.After(issuedBefore) {
+		issuedBefore = current.issuedBefore
+	}
+	if ok && current.until.After(until) {
+		until = current.until
+	}
+	l.users[userID] = userRevocation{issuedBefore: issuedBefore, until: until}
+}
+
+// IsRevoked reports whether the claims belong to a revoked token or user.
+func (l *RevocationList) IsRevoked(claims *Claims) bool {
+	now := time.Now()
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if claims.ID != "" {
+		if expires, ok := l.tokens[claims.ID]; ok && now.Before(expires) {
+			return true
+		}
+	}
+
+	if r, ok := l.users[claims.UserID]; ok && now.Before(r.until) {
+		// Tokens without iat cannot prove they postdate the revocation
+		if claims.IssuedAt == nil || claims.IssuedAt.Before(r.issuedBefore) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Len returns the number of live token and user entries.
+func (l *RevocationList) Len() (tokens, users int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.pruneLocked(time.Now())
+	return len(l.tokens), len(l.users)
+}
+
+// maybePruneLocked sweeps expired entries at most once per pruneInterval,
+// so bulk revocations do not rescan the whole list on every insert.
+func (l *RevocationList) maybePruneLocked(now time.Time) {
+	if now.Sub(l.pruned) >= pruneInterval {
+		l.pruneLocked(now)
+	}
+}
+
+func (l *RevocationList) pruneLocked(now time.Time) {
+	l.pruned = now
+	for jti, expires := range l.tokens {
+		if !now.Before(expires) {
+			delete(l.tokens, jti)
+		}
+	}
+	for user, r := range l.users {
+		if !now.Before(r.until) {
+			delete(l.users, user)
+		}
+	}
+}
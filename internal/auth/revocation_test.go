@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func issuedAt(userID string, t time.Time) *Claims {
+	return &Claims{
+		UserID:           userID,
+		RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(t)},
+	}
+}
+
+func TestRevokeUserKeepsTokensFromTheSameSecond(t *testing.T) {
+	l := NewRevocationList()
+	revokedAt := time.Now().Truncate(time.Second).Add(300 * time.Millisecond)
+	l.RevokeUser("alice", revokedAt, time.Now().Add(time.Hour))
+
+	if !l.IsRevoked(issuedAt("alice", revokedAt.Add(-time.Second))) {
+		t.Error("token issued a second before the revocation was not revoked")
+	}
+	// A re-login right after "log out everywhere" has the same iat second
+	if l.IsRevoked(issuedAt("alice", revokedAt.Add(500*time.Millisecond))) {
+		t.Error("token issued in the revocation's second was revoked")
+	}
+	if l.IsRevoked(issuedAt("bob", revokedAt.Add(-time.Second))) {
+		t.Error("another user's token was revoked")
+	}
+	if !l.IsRevoked(&Claims{UserID: "alice"}) {
+		t.Error("token without iat was not revoked")
+	}
+}
+
+func TestRevocationsExpire(t *testing.T) {
+	l := NewRevocationList()
+	l.RevokeToken("jti-1", time.Now().Add(-time.Second))
+	l.RevokeUser("alice", time.Now(), time.Now().Add(-time.Second))
+
+	if l.IsRevoked(&Claims{UserID: "alice", RegisteredClaims: jwt.RegisteredClaims{ID: "jti-1"}}) {
+		t.Error("expired revocation still applies")
+	}
+	if tokens, users := l.Len(); tokens != 0 || users != 0 {
+		t.Errorf("Len() = %d, %d after expiry, want 0, 0", tokens, users)
+	}
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SessionCookie stores the JWT in an HttpOnly cookie for browser clients,
+// paired with a readable CSRF cookie for double-submit validation.
+type SessionCookie struct {
+	Name       string
+	CSRFName   string
+	CSRFHeader string
+	Domain     string
+	Path       string
+	SameSite   http.SameSite
+	MaxAge     time.Duration // 0 makes browser-session cookies
+	Secure     bool
+}
+
+// Set writes the session cookie and a fresh CSRF token cookie.
+func (c *SessionCookie) Set(w http.ResponseWriter, token string) error {
+	csrf := make([]byte, 32)
+	if _, err := rand.Read(csrf); err != nil {
+		return fmt.Errorf("generate csrf token: %w", err)
+	}
+
+	http.SetCookie(w, c.cookie(c.Name, token, true))
+	// Readable by scripts so the SPA can echo it in the CSRF header
+	http.SetCookie(w, c.cookie(c.CSRFName, hex.EncodeToString(csrf), false))
+	return nil
+}
+
+// Clear expires the session and CSRF cookies.
+func (c *SessionCookie) Clear(w http.ResponseWriter) {
+	for _, name := range []string{c.Name, c.CSRFName} {
+		cookie := c.cookie(name, "", name == c.Name)
+		cookie.MaxAge = -1
+		cookie.Expires = time.Unix(0, 0)
+		http.SetCookie(w, cookie)
+	}
+}
+
+// VerifyCSRF checks that the CSRF header matches the CSRF cookie.
+// Safe methods are not checked.
+func (c *SessionCookie) VerifyCSRF(r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+
+	cookie, err := r.Cookie(c.CSRFName)
+	if err != nil || cookie.Value == "" {
+		return fmt.Errorf("missing csrf cookie")
+	}
+	header := r.Header.Get(c.CSRFHeader)
+	if header == "" {
+		return fmt.Errorf("missing %s header", c.CSRFHeader)
+	}
+	if subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+		return fmt.Errorf("csrf token mismatch")
+	}
+	return nil
+}
+
+func (c *SessionCookie) cookie(name, value string, httpOnly bool) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		SameSite: c.SameSite,
+		Secure:   c.Secure,
+		HttpOnly: httpOnly,
+	}
+	if c.MaxAge > 0 {
+		cookie.MaxAge = int(c.MaxAge.Seconds())
+	}
+	return cookie
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// VerifierSet routes bearer tokens to the verifier for their issuer:
+// external OIDC issuers by "iss", session-agent JWTs by default, and
+// opaque (non-JWT) tokens to introspection endpoints.
+type VerifierSet struct {
+	local         *JWTVerifier
+	issuers       map[string]*OIDCProvider
+	introspectors []*Introspector
+}
+
+// NewVerifierSet creates a verifier set around the session-agent verifier.
+func NewVerifierSet(local *JWTVerifier) *VerifierSet {
+	return &VerifierSet{
+		local:   local,
+		issuers: make(map[string]*OIDCProvider),
+	}
+}
+
+// AddIssuer registers an external OIDC issuer.
+func (s *VerifierSet) AddIssuer(p *OIDCProvider) {
+	s.issuers[p.Issuer()] = p
+}
+
+// AddIntrospector registers an introspection endpoint for opaque tokens.
+func (s *VerifierSet) AddIntrospector(in *Introspector) {
+	s.introspectors = append(s.introspectors, in)
+}
+
+// Verify validates a bearer token with the matching verifier.
+func (s *VerifierSet) Verify(ctx context.Context, token string) (*Claims, error) {
+	unverified := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, unverified); err == nil {
+		iss, _ := unverified["iss"].(string)
+		if p, ok := s.issuers[iss]; ok {
+			return p.Verify(ctx, token)
+		}
+		return s.local.Verify(token)
+	}
+
+	if len(s.introspectors) == 0 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var lastErr error
+	for _, in := range s.introspectors {
+		claims, err := in.Introspect(ctx, token)
+		if err == nil {
+			return claims, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// Keys returns all known verification keys, sorted by issuer and ID.
+func (s *VerifierSet) Keys() []KeyInfo {
+	keys := s.local.Keys()
+	for _, p := range s.issuers {
+		keys = append(keys, p.Keys()...)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Issuer != keys[j].Issuer {
+			return keys[i].Issuer < keys[j].Issuer
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+// Refresh prefetches keys for all OIDC issuers, returning the first error.
+func (s *VerifierSet) Refresh(ctx context.Context) error {
+	var firstErr error
+	for iss, p := range s.issuers {
+		if err := p.Refresh(ctx); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", iss, err)
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jhaveripatric/agent-gateway/internal/rpc"
+)
+
+// Entry is a cached response.
+type Entry struct {
+	Status      int
+	ContentType string
+	// Header holds response headers safe to replay to other callers.
+	Header http.Header
+	Body   []byte
+	ETag   string
+	Stored time.Time
+	TTL    time.Duration
+	// Stale is how long past TTL the entry may be served while it is
+	// refreshed in the background.
+	Stale time.Duration
+}
+
+// Fresh reports whether the entry is within its TTL.
+func (e *Entry) Fresh(now time.Time) bool {
+	return now.Sub(e.Stored) < e.TTL
+}
+
+// Usable reports whether the entry may still be served, fresh or stale.
+func (e *Entry) Usable(now time.Time) bool {
+	return now.Sub(e.Stored) < e.TTL+e.Stale
+}
+
+// Age returns the time since the entry was stored.
+func (e *Entry) Age(now time.Time) time.Duration {
+	return now.Sub(e.Stored)
+}
+
+type item struct {
+	entry *Entry
+	tag   string
+}
+
+// Cache holds responses keyed by request and tagged by action, so bus
+// events can invalidate every entry of an action at once.
+type Cache struct {
+	mu         sync.Mutex
+	items      map[string]item
+	maxEntries int
+	rules      map[string][]string // routing key pattern -> tags
+}
+
+// New creates a cache holding at most maxEntries responses.
+func New(maxEntries int) *Cache {
+	return &Cache{
+		items:      make(map[string]item),
+		maxEntries: maxEntries,
+	}
+}
+
+// Get returns the usable entry for key, or nil.
+func (c *Cache) Get(key string) *Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	it, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	if !it.entry.Usable(time.Now()) {
+		delete(c.items, key)
+		return nil
+	}
+	return it.entry
+}
+
+// Set stores an entry under key with the action's tag.
+func (c *Cache) Set(key, tag string, e *Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.items[key]; !ok && len(c.items) >= c.maxEntries {
+		c.evict()
+	}
+	c.items[key] = item{entry: e, tag: tag}
+}
+
+// evict drops expired entries, or the oldest entry if none have expired.
+// Called with c.mu held.
+func (c *Cache) evict() {
+	now := time.Now()
+	var oldestKey string
+	var oldest time.Time
+	for k, it := range c.items {
+		if !it.entry.Usable(now) {
+			delete(c.items, k)
+			continue
+		}
+		if oldestKey == "" || it.entry.Stored.Before(oldest) {
+			oldestKey, oldest = k, it.entry.Stored
+		}
+	}
+	if len(c.items) >= c.maxEntries && oldestKey != "" {
+		delete(c.items, oldestKey)
+	}
+}
+
+// Invalidate removes every entry with the tag and returns how many.
+func (c *Cache) Invalidate(tag string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for k, it := range c.items {
+		if it.tag == tag {
+			delete(c.items, k)
+			n++
+		}
+	}
+	return n
+}
+
+// SetRules replaces the routing key patterns that invalidate tags.
+func (c *Cache) SetRules(rules map[string][]string) {
+	c.mu.Lock()
+	c.rules = rules
+	c.mu.Unlock()
+}
+
+// InvalidateEvent invalidates the tags whose patterns match routingKey and
+// returns the number of entries removed.
+func (c *Cache) InvalidateEvent(routingKey string) int {
+	c.mu.Lock()
+	var tags []string
+	for pattern, t := range c.rules {
+		if rpc.TopicMatch(pattern, routingKey) {
+			tags = append(tags, t...)
+		}
+	}
+	c.mu.Unlock()
+
+	n := 0
+	for _, tag := range tags {
+		n += c.Invalidate(tag)
+	}
+	return n
+}
+
+// Len returns the number of cached entries.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
@@ -0,0 +1,51 @@
+package cache
+
+import "sync"
+
+// Group collapses concurrent calls with the same key into one.
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	done  chan struct{}
+	entry *Entry
+}
+
+// NewGroup creates an empty group.
+func NewGroup() *Group {
+	return &Group{calls: make(map[string]*call)}
+}
+
+// Do runs fn once for concurrent callers with the same key. shared reports
+// whether the result came from another caller's fn.
+func (g *Group) Do(key string, fn func() *Entry) (entry *Entry, shared bool) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-c.done
+		return c.entry, true
+	}
+	c := &call{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+
+	c.entry = fn()
+	return c.entry, false
+}
+
+// Inflight reports whether a call for key is running.
+func (g *Group) Inflight(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, ok := g.calls[key]
+	return ok
+}
@@ -2,8 +2,17 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/jhaveripatric/agent-gateway/internal/envsubst"
+	"github.com/jhaveripatric/agent-gateway/internal/yamlcheck"
+	amqp "github.com/rabbitmq/amqp091-go"
 	"gopkg.in/yaml.v3"
 )
 
@@ -14,24 +23,513 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("read config file: %w", err)
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
 		return nil, fmt.Errorf("parse config: %w", err)
 	}
+	if err := envsubst.ExpandNode(&doc); err != nil {
+		return nil, fmt.Errorf("expand config: %w", err)
+	}
 
-	if err := validate(&cfg); err != nil {
-		return nil, fmt.Errorf("validate config: %w", err)
+	// Every problem is collected so one run reports them all
+	var cfg Config
+	report := yamlcheck.NewReport(&doc)
+	report.Decode(&doc, &cfg)
+	if err := applyEnv(&cfg, os.Environ()); err != nil {
+		report.Add("", err)
 	}
+	validate(&cfg, report)
+	if err := report.Err(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	cfg.Dir = filepath.Dir(path)
+	resolvePaths(&cfg)
 
 	return &cfg, nil
 }
 
-func validate(cfg *Config) error {
+// resolvePaths makes relative file paths relative to the config file
+// rather than the working directory. Manifest paths are resolved by the
+// manifest loader.
+func resolvePaths(cfg *Config) {
+	paths := []*string{
+		&cfg.Gateway.TLS.CertFile,
+		&cfg.Gateway.TLS.KeyFile,
+		&cfg.Gateway.TLS.ClientCAFile,
+		&cfg.Auth.APIKeys.File,
+		&cfg.Webhooks.StoreDir,
+	}
+	for i := range cfg.Registration.Keys {
+		paths = append(paths, &cfg.Registration.Keys[i].PublicKeyFile)
+	}
+	for _, p := range paths {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(cfg.Dir, *p)
+		}
+	}
+}
+
+// validate sets defaults and records every problem in r, against the
+// key it concerns.
+func validate(cfg *Config, r *yamlcheck.Report) {
 	if cfg.Gateway.Port == 0 {
 		cfg.Gateway.Port = 8080
 	}
 	if cfg.Gateway.Port < 1 || cfg.Gateway.Port > 65535 {
-		return fmt.Errorf("invalid port: %d", cfg.Gateway.Port)
+		r.Addf("gateway.port", "invalid port: %d", cfg.Gateway.Port)
+	}
+	if err := validateLimits(&cfg.Gateway); err != nil {
+		r.Add("gateway", err)
+	}
+	if err := validateTLS(&cfg.Gateway.TLS); err != nil {
+		r.Add("gateway.tls", err)
+	}
+	for i, origin := range cfg.Gateway.CORS.AllowedOrigins {
+		if err := validateOrigin(origin); err != nil {
+			r.Add(fmt.Sprintf("gateway.cors.allowed_origins[%d]", i), err)
+		}
+	}
+	if cfg.Auth.APIKeys.Header == "" {
+		cfg.Auth.APIKeys.Header = "X-API-Key"
+	}
+	if err := validateSessionCookie(&cfg.Auth.SessionCookie); err != nil {
+		r.Add("auth.session_cookie", err)
+	}
+	setRevocationDefaults(&cfg.Auth.Revocation)
+	for i, o := range cfg.Auth.OIDC {
+		if o.Issuer == "" {
+			r.Addf(fmt.Sprintf("auth.oidc[%d]", i), "issuer is required")
+		}
+	}
+	for i, in := range cfg.Auth.Introspection {
+		if in.Endpoint == "" {
+			r.Addf(fmt.Sprintf("auth.introspection[%d]", i), "endpoint is required")
+		}
+	}
+	validateIngress(&cfg.Ingress, cfg.Gateway.MaxBodyBytes, r)
+	if cfg.Idempotency.Header == "" {
+		cfg.Idempotency.Header = "Idempotency-Key"
+	}
+	if cfg.Idempotency.TTL == 0 {
+		cfg.Idempotency.TTL = 24 * time.Hour
+	}
+	if cfg.Cache.MaxEntries == 0 {
+		cfg.Cache.MaxEntries = 10000
+	}
+	if err := validateConcurrency(&cfg.Limits); err != nil {
+		r.Add("limits", err)
+	}
+	if cfg.Health.HeartbeatTimeout == 0 {
+		cfg.Health.HeartbeatTimeout = 30 * time.Second
+	}
+	if cfg.Health.ProbeInterval < 0 || cfg.Health.HeartbeatTimeout < 0 {
+		r.Addf("health", "durations must not be negative")
+	}
+	if err := validateDiscovery(&cfg.Discovery); err != nil {
+		r.Add("discovery", err)
+	}
+	validateRegistration(&cfg.Registration, r)
+	validateWebhooks(&cfg.Webhooks, r)
+	validateTenancy(&cfg.Tenancy, r)
+	for i, proxy := range cfg.Gateway.TrustedProxies {
+		if err := validateCIDR(proxy); err != nil {
+			r.Add(fmt.Sprintf("gateway.trusted_proxies[%d]", i), err)
+		}
+	}
+	validateRabbitMQ(&cfg.Infrastructure.RabbitMQ, r)
+	validateAgents(cfg.Agents, r)
+}
+
+func validateRabbitMQ(c *RabbitMQConfig, r *yamlcheck.Report) {
+	if c.URL == "" {
+		r.Addf("infrastructure.rabbitmq.url", "url is required")
+	} else if _, err := amqp.ParseURI(c.URL); err != nil {
+		// The URL itself may carry a password, so only the reason is shown
+		r.Addf("infrastructure.rabbitmq.url", "invalid AMQP URL: %v", err)
+	}
+	if c.Exchange == "" {
+		r.Addf("infrastructure.rabbitmq.exchange", "exchange is required")
+	}
+}
+
+func validateAgents(agents []AgentRef, r *yamlcheck.Report) {
+	seen := make(map[string]int, len(agents))
+	for i, a := range agents {
+		path := fmt.Sprintf("agents[%d]", i)
+		if a.Name == "" || a.ManifestPath == "" {
+			r.Addf(path, "name and manifest_path are required")
+			continue
+		}
+		if first, ok := seen[a.Name]; ok {
+			r.Addf(path+".name", "duplicate agent %q, first listed as agents[%d]", a.Name, first)
+			continue
+		}
+		seen[a.Name] = i
+	}
+}
+
+// validateOrigin accepts "*" or an origin such as https://app.example.com,
+// optionally with one "*" wildcard, e.g. https://*.example.com.
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	if strings.Count(origin, "*") > 1 {
+		return fmt.Errorf("invalid origin %q: at most one wildcard", origin)
+	}
+	u, err := url.Parse(strings.Replace(origin, "*", "wildcard", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid origin %q: want scheme://host[:port]", origin)
+	}
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("invalid origin %q: origins have no path, query or credentials", origin)
+	}
+	return nil
+}
+
+func validateSessionCookie(c *SessionCookieConfig) error {
+	if c.Name == "" {
+		c.Name = "agw_session"
+	}
+	if c.CSRFName == "" {
+		c.CSRFName = "agw_csrf"
+	}
+	if c.CSRFHeader == "" {
+		c.CSRFHeader = "X-CSRF-Token"
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	if c.SameSite == "" {
+		c.SameSite = "lax"
+	}
+
+	switch c.SameSite {
+	case "lax", "strict":
+	case "none":
+		if c.Insecure {
+			return fmt.Errorf("same_site none requires secure cookies")
+		}
+	default:
+		return fmt.Errorf("invalid same_site: %s", c.SameSite)
+	}
+	if c.Name == c.CSRFName {
+		return fmt.Errorf("name and csrf_name must differ")
+	}
+	return nil
+}
+
+func setRevocationDefaults(r *RevocationConfig) {
+	if len(r.Events) == 0 {
+		r.Events = []string{"auth.session.revoked"}
+	}
+	if r.SnapshotEvent == "" {
+		r.SnapshotEvent = "io.agenteco.auth.session.revocations.requested.v1"
+	}
+	if r.SnapshotTimeout == 0 {
+		r.SnapshotTimeout = 5 * time.Second
+	}
+	if r.MaxTokenTTL == 0 {
+		r.MaxTokenTTL = 24 * time.Hour
+	}
+}
+
+func validateTenancy(t *TenancyConfig, r *yamlcheck.Report) {
+	if !t.Enabled {
+		return
+	}
+	if len(t.Sources) == 0 {
+		t.Sources = []string{"claim", "header"}
+	}
+	if t.Claim == "" {
+		t.Claim = "tenant"
+	}
+	if t.Header == "" {
+		t.Header = "X-Tenant-ID"
+	}
+	if t.PathPrefix == "" {
+		t.PathPrefix = "/t"
+	}
+
+	for i, src := range t.Sources {
+		switch src {
+		case "claim", "header", "path":
+		case "subdomain":
+			if t.BaseDomain == "" {
+				r.Addf("tenancy.base_domain", "subdomain source requires base_domain")
+			}
+		default:
+			r.Addf(fmt.Sprintf("tenancy.sources[%d]", i), "invalid source: %s", src)
+		}
+	}
+	if len(t.Tenants) == 0 {
+		r.Addf("tenancy.tenants", "no tenants registered")
+	}
+}
+
+func validateIngress(in *IngressConfig, maxBody int64, r *yamlcheck.Report) {
+	if !in.Enabled {
+		return
+	}
+	if in.Path == "" {
+		in.Path = "/events"
+	}
+	if !strings.HasPrefix(in.Path, "/") {
+		r.Addf("ingress.path", "path must start with /")
+	}
+	if in.Auth == "" {
+		in.Auth = "mtls|bearer|api_key"
+	}
+	if in.Timeout == 0 {
+		in.Timeout = 30 * time.Second
+	}
+	if in.MaxBodyBytes == 0 {
+		in.MaxBodyBytes = maxBody
+	}
+	for i, c := range in.Callers {
+		path := fmt.Sprintf("ingress.callers[%d]", i)
+		if c.Method == "" || c.ID == "" || len(c.Allow) == 0 {
+			r.Addf(path, "method, id and allow are required")
+			continue
+		}
+		switch c.Method {
+		case "mtls", "api_key", "bearer", "oidc", "introspection":
+		default:
+			r.Addf(path+".method", "unknown method %q", c.Method)
+		}
+	}
+}
+
+func validateConcurrency(l *LimitsConfig) error {
+	if !l.Enabled {
+		return nil
+	}
+	if l.MaxInFlight < 0 {
+		return fmt.Errorf("invalid max_in_flight: %d", l.MaxInFlight)
+	}
+	if l.RetryAfter == 0 {
+		l.RetryAfter = time.Second
+	}
+	if err := validateAgentLimit(&l.Agent); err != nil {
+		return fmt.Errorf("agent: %w", err)
+	}
+	for name, a := range l.Agents {
+		mergeAgentLimit(&a, l.Agent)
+		if err := validateAgentLimit(&a); err != nil {
+			return fmt.Errorf("agents.%s: %w", name, err)
+		}
+		l.Agents[name] = a
+	}
+	return nil
+}
+
+func validateAgentLimit(a *AgentLimitConfig) error {
+	if a.MaxInFlight < 0 || a.QueueSize < 0 || a.MinInFlight < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	if a.QueueTimeout == 0 {
+		a.QueueTimeout = time.Second
+	}
+	switch a.Adaptive {
+	case "":
+	case "aimd":
+		if a.MaxInFlight == 0 {
+			return fmt.Errorf("adaptive limits need max_in_flight")
+		}
+		if a.TargetLatency == 0 {
+			return fmt.Errorf("adaptive limits need target_latency")
+		}
+	default:
+		return fmt.Errorf("unknown adaptive mode %q", a.Adaptive)
+	}
+	return nil
+}
+
+// mergeAgentLimit fills zero fields of an override from the defaults.
+func mergeAgentLimit(a *AgentLimitConfig, def AgentLimitConfig) {
+	if a.MaxInFlight == 0 {
+		a.MaxInFlight = def.MaxInFlight
+	}
+	if a.QueueSize == 0 {
+		a.QueueSize = def.QueueSize
+	}
+	if a.QueueTimeout == 0 {
+		a.QueueTimeout = def.QueueTimeout
+	}
+	if a.Adaptive == "" {
+		a.Adaptive = def.Adaptive
+	}
+	if a.MinInFlight == 0 {
+		a.MinInFlight = def.MinInFlight
+	}
+	if a.TargetLatency == 0 {
+		a.TargetLatency = def.TargetLatency
+	}
+}
+
+func validateDiscovery(d *DiscoveryConfig) error {
+	if d.ManifestFile == "" {
+		d.ManifestFile = "agent.yaml"
+	}
+	for _, p := range d.ManifestGlobs {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("manifest_globs: %q: %w", p, err)
+		}
+	}
+	for _, p := range append(d.Include, d.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("include/exclude: %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+func validateRegistration(reg *RegistrationConfig, r *yamlcheck.Report) {
+	if !reg.Enabled {
+		return
+	}
+	if len(reg.AnnounceEvents) == 0 {
+		reg.AnnounceEvents = []string{"agent.manifest.announced"}
+	}
+	if len(reg.WithdrawEvents) == 0 {
+		reg.WithdrawEvents = []string{"agent.manifest.withdrawn"}
+	}
+	if len(reg.Keys) == 0 {
+		r.Addf("registration", "at least one key is required")
+	}
+	for i, k := range reg.Keys {
+		if k.ID == "" || k.PublicKeyFile == "" || len(k.Agents) == 0 {
+			r.Addf(fmt.Sprintf("registration.keys[%d]", i), "id, public_key_file and agents are required")
+		}
+	}
+}
+
+func validateWebhooks(w *WebhooksConfig, r *yamlcheck.Report) {
+	if !w.Enabled {
+		return
+	}
+	if w.Queue == "" {
+		w.Queue = "agent-gateway.webhooks"
+	}
+	if w.StoreDir == "" {
+		w.StoreDir = "data/webhooks"
+	}
+	if w.Workers == 0 {
+		w.Workers = 4
+	}
+	if w.DedupeTTL == 0 {
+		w.DedupeTTL = 24 * time.Hour
+	}
+	seen := make(map[string]int)
+	for i := range w.Subscriptions {
+		s := &w.Subscriptions[i]
+		path := fmt.Sprintf("webhooks.subscriptions[%d]", i)
+		if s.ID == "" || s.URL == "" || len(s.Events) == 0 {
+			r.Addf(path, "id, url and events are required")
+		}
+		if first, ok := seen[s.ID]; ok && s.ID != "" {
+			r.Addf(path+".id", "duplicate id %q, first listed as webhooks.subscriptions[%d]", s.ID, first)
+		} else {
+			seen[s.ID] = i
+		}
+		if s.Secret == "" {
+			r.Addf(path+".secret", "secret is required")
+		}
+		if s.URL != "" {
+			u, err := url.Parse(s.URL)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				r.Addf(path+".url", "invalid url %q", RedactURL(s.URL))
+			}
+		}
+		if s.MaxAttempts == 0 {
+			s.MaxAttempts = 8
+		}
+		if s.InitialBackoff == 0 {
+			s.InitialBackoff = time.Second
+		}
+		if s.MaxBackoff == 0 {
+			s.MaxBackoff = 10 * time.Minute
+		}
+		if s.Timeout == 0 {
+			s.Timeout = 10 * time.Second
+		}
+	}
+}
+
+func validateCIDR(s string) error {
+	var err error
+	if strings.Contains(s, "/") {
+		_, err = netip.ParsePrefix(s)
+	} else {
+		_, err = netip.ParseAddr(s)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid CIDR %q", s)
+	}
+	return nil
+}
+
+func validateLimits(g *GatewayConfig) error {
+	if g.ReadHeaderTimeout == 0 {
+		g.ReadHeaderTimeout = 5 * time.Second
+	}
+	if g.ReadTimeout == 0 {
+		g.ReadTimeout = 30 * time.Second
+	}
+	if g.WriteTimeout == 0 {
+		g.WriteTimeout = 60 * time.Second
+	}
+	if g.IdleTimeout == 0 {
+		g.IdleTimeout = 120 * time.Second
+	}
+	if g.MaxHeaderBytes == 0 {
+		g.MaxHeaderBytes = 1 << 20
+	}
+	if g.MaxBodyBytes == 0 {
+		g.MaxBodyBytes = 1 << 20
+	}
+
+	if g.ReadHeaderTimeout < 0 || g.ReadTimeout < 0 || g.WriteTimeout < 0 || g.IdleTimeout < 0 {
+		return fmt.Errorf("server timeouts must not be negative")
+	}
+	if g.MaxHeaderBytes < 0 {
+		return fmt.Errorf("invalid max_header_bytes: %d", g.MaxHeaderBytes)
+	}
+	if g.MaxBodyBytes < 0 {
+		return fmt.Errorf("invalid max_body_bytes: %d", g.MaxBodyBytes)
+	}
+	if g.MaxEventBytes < 0 {
+		return fmt.Errorf("invalid max_event_bytes: %d", g.MaxEventBytes)
+	}
+	return nil
+}
+
+func validateTLS(t *TLSConfig) error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("cert_file and key_file must be set together")
+	}
+
+	if t.MinVersion == "" {
+		t.MinVersion = "1.2"
+	}
+	if t.MinVersion != "1.2" && t.MinVersion != "1.3" {
+		return fmt.Errorf("invalid min_version: %s", t.MinVersion)
+	}
+
+	if t.ClientAuth == "" {
+		t.ClientAuth = "none"
+	}
+	switch t.ClientAuth {
+	case "none":
+	case "optional", "require":
+		if !t.Enabled() {
+			return fmt.Errorf("client_auth %s requires cert_file and key_file", t.ClientAuth)
+		}
+		if t.ClientCAFile == "" {
+			return fmt.Errorf("client_auth %s requires client_ca_file", t.ClientAuth)
+		}
+	default:
+		return fmt.Errorf("invalid client_auth: %s", t.ClientAuth)
 	}
 	return nil
 }
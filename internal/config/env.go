@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/jhaveripatric/agent-gateway/internal/envsubst"
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix starts environment variables that override config keys.
+const envPrefix = "AGENT_GATEWAY_"
+
+// applyEnv overrides config keys from AGENT_GATEWAY_* variables. The rest
+// of the name is the key path in upper case with "_" between segments,
+// e.g. AGENT_GATEWAY_INFRASTRUCTURE_RABBITMQ_URL. A _FILE suffix reads the
+// value from the named file. Lists take comma-separated values; keys
+// inside lists and maps cannot be overridden.
+//
+// Variables matching no key are only logged: Kubernetes injects
+// service-link variables such as AGENT_GATEWAY_SERVICE_HOST into every pod
+// of a namespace with a Service named agent-gateway.
+func applyEnv(cfg *Config, environ []string) error {
+	var unknown []string
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		key, ok := strings.CutPrefix(name, envPrefix)
+		if !ok {
+			continue
+		}
+
+		field, ok := lookupKey(reflect.ValueOf(cfg).Elem(), key)
+		if !ok {
+			// A key ending in _file, like tls cert_file, matched above
+			base, isFile := strings.CutSuffix(key, "_FILE")
+			if isFile {
+				field, ok = lookupKey(reflect.ValueOf(cfg).Elem(), base)
+			}
+			if !ok {
+				unknown = append(unknown, name)
+				continue
+			}
+			secret, err := envsubst.ReadSecret(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			value = secret
+		}
+
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		log.Printf("Warning: ignoring environment variables that match no config key: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// lookupKey finds the struct field addressed by an upper-case key path.
+// Segments are matched against yaml tags, so keys containing "_" such as
+// max_body_bytes resolve without a separate delimiter.
+func lookupKey(v reflect.Value, key string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := range t.NumField() {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		seg := strings.ToUpper(tag)
+		f := v.Field(i)
+		if key == seg {
+			return f, settable(f)
+		}
+		rest, ok := strings.CutPrefix(key, seg+"_")
+		if ok && f.Kind() == reflect.Struct {
+			if found, ok := lookupKey(f, rest); ok {
+				return found, true
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func settable(f reflect.Value) bool {
+	switch f.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return f.Type().Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+// setField decodes value into f with YAML scalar rules, so durations like
+// "30s" and booleans parse as they would in the file.
+func setField(f reflect.Value, value string) error {
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+		return nil
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		f.Set(reflect.ValueOf(items))
+		return nil
+	}
+	n := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	if err := n.Decode(f.Addr().Interface()); err != nil {
+		return fmt.Errorf("invalid %s value %q", f.Type(), value)
+	}
+	return nil
+}
+
+// redacted replaces secret values when printing.
+const redacted = "REDACTED"
+
+// Redacted returns a copy of the config with secrets replaced, for
+// printing.
+func (c *Config) Redacted() *Config {
+	r := *c
+	r.Infrastructure.RabbitMQ.URL = RedactURL(c.Infrastructure.RabbitMQ.URL)
+
+	r.Auth.Introspection = slices.Clone(c.Auth.Introspection)
+	for i := range r.Auth.Introspection {
+		if r.Auth.Introspection[i].ClientSecret != "" {
+			r.Auth.Introspection[i].ClientSecret = redacted
+		}
+	}
+
+	r.Webhooks.Subscriptions = slices.Clone(c.Webhooks.Subscriptions)
+	for i := range r.Webhooks.Subscriptions {
+		s := &r.Webhooks.Subscriptions[i]
+		s.URL = RedactURL(s.URL)
+		if s.Secret != "" {
+			s.Secret = redacted
+		}
+	}
+	return &r
+}
+
+// RedactURL hides the password in a URL's user info, for printing.
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		// Unparseable URLs may still embed credentials
+		if strings.Contains(raw, "@") {
+			return redacted
+		}
+		return raw
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	return u.String()
+}
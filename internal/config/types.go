@@ -1,18 +1,70 @@
 package config
 
+import "time"
+
 // Config holds all gateway configuration.
 type Config struct {
-	Name           string         `yaml:"name"`
-	Version        string         `yaml:"version"`
-	Gateway        GatewayConfig  `yaml:"gateway"`
-	Infrastructure InfraConfig    `yaml:"infrastructure"`
-	Agents         []AgentRef     `yaml:"agents"`
+	Name           string             `yaml:"name"`
+	Version        string             `yaml:"version"`
+	Gateway        GatewayConfig      `yaml:"gateway"`
+	Admin          AdminConfig        `yaml:"admin"`
+	Auth           AuthConfig         `yaml:"auth"`
+	Tenancy        TenancyConfig      `yaml:"tenancy"`
+	Ingress        IngressConfig      `yaml:"ingress"`
+	Webhooks       WebhooksConfig     `yaml:"webhooks"`
+	Idempotency    IdempotencyConfig  `yaml:"idempotency"`
+	Cache          CacheConfig        `yaml:"cache"`
+	Limits         LimitsConfig       `yaml:"limits"`
+	Health         HealthConfig       `yaml:"health"`
+	Registration   RegistrationConfig `yaml:"registration"`
+	Infrastructure InfraConfig        `yaml:"infrastructure"`
+	Agents         []AgentRef         `yaml:"agents"`
+	Discovery      DiscoveryConfig    `yaml:"discovery"`
+
+	// Dir is the directory holding the config file. Relative file paths
+	// in the config resolve against it.
+	Dir string `yaml:"-"`
 }
 
 // GatewayConfig holds HTTP server settings.
 type GatewayConfig struct {
 	Port int        `yaml:"port"`
 	CORS CORSConfig `yaml:"cors"`
+	TLS  TLSConfig  `yaml:"tls"`
+
+	// TrustedProxies lists CIDRs whose forwarding headers are honoured
+	// when resolving the client IP and scheme.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes"`
+
+	// MaxBodyBytes is the default request body limit for actions that do
+	// not set their own max_body_bytes.
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+
+	// MaxEventBytes limits the encoded event published for a request.
+	// 0 allows the action's body limit plus room for gateway fields and
+	// the CloudEvent envelope.
+	MaxEventBytes int64 `yaml:"max_event_bytes"`
+}
+
+// TLSConfig holds listener TLS settings. TLS is enabled when both
+// CertFile and KeyFile are set; the files are reloaded when they change.
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	MinVersion   string `yaml:"min_version"`    // "1.2" (default) or "1.3"
+	ClientCAFile string `yaml:"client_ca_file"` // CA bundle for client certificates
+	ClientAuth   string `yaml:"client_auth"`    // "none" (default), "optional" or "require"
+}
+
+// Enabled reports whether the listener should serve TLS.
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
 }
 
 // CORSConfig holds CORS settings.
@@ -20,6 +72,222 @@ type CORSConfig struct {
 	AllowedOrigins []string `yaml:"allowed_origins"`
 }
 
+// AdminConfig holds settings for the admin listener.
+// The admin API is disabled when Addr is empty.
+type AdminConfig struct {
+	Addr string `yaml:"addr"`
+}
+
+// AuthConfig holds gateway-level authentication settings.
+type AuthConfig struct {
+	APIKeys       APIKeyConfig        `yaml:"api_keys"`
+	SessionCookie SessionCookieConfig `yaml:"session_cookie"`
+	Revocation    RevocationConfig    `yaml:"revocation"`
+	OIDC          []OIDCConfig        `yaml:"oidc"`
+	Introspection []IntrospectConfig  `yaml:"introspection"`
+}
+
+// OIDCConfig configures an external OpenID Connect issuer whose JWTs are
+// verified with keys from its discovery document's JWKS.
+type OIDCConfig struct {
+	Name         string        `yaml:"name"`
+	Issuer       string        `yaml:"issuer"`
+	Audience     string        `yaml:"audience"`
+	DiscoveryURL string        `yaml:"discovery_url"`
+	JWKSRefresh  time.Duration `yaml:"jwks_refresh"`
+	Claims       ClaimsMapping `yaml:"claims"`
+}
+
+// IntrospectConfig configures an RFC 7662 endpoint for opaque tokens.
+type IntrospectConfig struct {
+	Name         string        `yaml:"name"`
+	Endpoint     string        `yaml:"endpoint"`
+	ClientID     string        `yaml:"client_id"`
+	ClientSecret string        `yaml:"client_secret"`
+	CacheTTL     time.Duration `yaml:"cache_ttl"`
+	// CacheSize bounds cached active tokens (default 10000) and
+	// InactiveCacheSize cached rejections (default 1000).
+	CacheSize         int           `yaml:"cache_size"`
+	InactiveCacheSize int           `yaml:"inactive_cache_size"`
+	Claims            ClaimsMapping `yaml:"claims"`
+}
+
+// ClaimsMapping names provider claims used for the user ID, username and
+// roles. Empty fields use sub, preferred_username and groups.
+type ClaimsMapping struct {
+	UserID   string   `yaml:"user_id"`
+	Username string   `yaml:"username"`
+	Roles    []string `yaml:"roles"`
+}
+
+// RevocationConfig configures the token deny-list fed by session-agent.
+type RevocationConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Events lists routing key patterns carrying revocations.
+	Events []string `yaml:"events"`
+	// SnapshotEvent is called at startup to rebuild the list.
+	SnapshotEvent   string        `yaml:"snapshot_event"`
+	SnapshotTimeout time.Duration `yaml:"snapshot_timeout"`
+	// MaxTokenTTL bounds user-wide revocations that carry no expiry.
+	MaxTokenTTL time.Duration `yaml:"max_token_ttl"`
+}
+
+// SessionCookieConfig configures cookie-based sessions for browser clients.
+// Cookies are Secure unless Insecure is set for local development.
+type SessionCookieConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	Name       string        `yaml:"name"`        // default "agw_session"
+	CSRFName   string        `yaml:"csrf_name"`   // default "agw_csrf"
+	CSRFHeader string        `yaml:"csrf_header"` // default "X-CSRF-Token"
+	Domain     string        `yaml:"domain"`
+	Path       string        `yaml:"path"`      // default "/"
+	SameSite   string        `yaml:"same_site"` // "lax" (default), "strict" or "none"
+	MaxAge     time.Duration `yaml:"max_age"`
+	Insecure   bool          `yaml:"insecure"`
+}
+
+// APIKeyConfig configures API key authentication. Keys are read from File,
+// which stores SHA-256 hashes, never plaintext keys.
+type APIKeyConfig struct {
+	File       string `yaml:"file"`
+	Header     string `yaml:"header"`      // default "X-API-Key"
+	QueryParam string `yaml:"query_param"` // empty disables query keys
+}
+
+// TenancyConfig controls tenant resolution and per-tenant settings.
+type TenancyConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Sources are tried in order: claim, header, subdomain, path.
+	Sources    []string       `yaml:"sources"`
+	Claim      string         `yaml:"claim"`       // default "tenant"
+	Header     string         `yaml:"header"`      // default "X-Tenant-ID"
+	BaseDomain string         `yaml:"base_domain"` // <tenant>.<base_domain>
+	PathPrefix string         `yaml:"path_prefix"` // default "/t": /t/<tenant>/api/...
+	Required   bool           `yaml:"required"`
+	Tenants    []TenantConfig `yaml:"tenants"`
+}
+
+// TenantConfig registers a tenant and its overrides.
+type TenantConfig struct {
+	ID               string   `yaml:"id"`
+	CORSOrigins      []string `yaml:"cors_origins"`       // added to gateway origins
+	RateLimit        string   `yaml:"rate_limit"`         // e.g. "100/s"
+	RoutingKeyPrefix string   `yaml:"routing_key_prefix"` // shards agent traffic
+}
+
+// IngressConfig configures the CloudEvents HTTP ingress for services.
+type IngressConfig struct {
+	Enabled      bool            `yaml:"enabled"`
+	Path         string          `yaml:"path"`    // default "/events"
+	Auth         string          `yaml:"auth"`    // default "mtls|bearer|api_key"
+	Timeout      time.Duration   `yaml:"timeout"` // synchronous reply limit, default 30s
+	MaxBodyBytes int64           `yaml:"max_body_bytes"`
+	Callers      []IngressCaller `yaml:"callers"`
+}
+
+// IngressCaller allowlists event types for a caller authenticated by
+// Method, so an end-user token cannot borrow a service's rights by sharing
+// its ID.
+type IngressCaller struct {
+	Method string   `yaml:"method"` // mtls, api_key, bearer, oidc or introspection
+	ID     string   `yaml:"id"`     // cert CN, key owner or token user ID
+	Allow  []string `yaml:"allow"`  // event type patterns, e.g. io.agenteco.billing.*
+}
+
+// IdempotencyConfig controls Idempotency-Key handling for unsafe actions.
+// Responses are kept in memory on each gateway instance.
+type IdempotencyConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Header  string        `yaml:"header"` // default "Idempotency-Key"
+	TTL     time.Duration `yaml:"ttl"`    // default 24h
+}
+
+// LimitsConfig bounds concurrent agent calls. Requests over a limit wait in
+// a bounded queue or are shed with 503 and Retry-After.
+type LimitsConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	MaxInFlight int           `yaml:"max_in_flight"` // across all agents, 0 unlimited
+	RetryAfter  time.Duration `yaml:"retry_after"`   // default 1s
+	// Agent holds defaults for every agent; Agents overrides them by name.
+	Agent  AgentLimitConfig            `yaml:"agent"`
+	Agents map[string]AgentLimitConfig `yaml:"agents"`
+}
+
+// AgentLimitConfig limits concurrent calls to one agent. Zero fields in
+// an override take the default's value.
+type AgentLimitConfig struct {
+	MaxInFlight  int           `yaml:"max_in_flight"` // 0 unlimited
+	QueueSize    int           `yaml:"queue_size"`
+	QueueTimeout time.Duration `yaml:"queue_timeout"` // default 1s
+	// Adaptive is "" (fixed limit) or "aimd", which lowers the limit while
+	// calls are slower than TargetLatency and raises it back as they recover.
+	Adaptive      string        `yaml:"adaptive"`
+	MinInFlight   int           `yaml:"min_in_flight"` // adaptive floor, default 1
+	TargetLatency time.Duration `yaml:"target_latency"`
+}
+
+// HealthConfig tracks agent liveness from heartbeat events and from
+// consumer counts on the queues named in manifests.
+type HealthConfig struct {
+	// HeartbeatEvents lists routing key patterns of agent heartbeats.
+	HeartbeatEvents  []string      `yaml:"heartbeat_events"`
+	HeartbeatTimeout time.Duration `yaml:"heartbeat_timeout"` // default 30s
+	ProbeInterval    time.Duration `yaml:"probe_interval"`    // 0 disables queue probes
+	// FailFast answers 503 for actions whose agent is known to be down.
+	FailFast bool `yaml:"fail_fast"`
+}
+
+// Enabled reports whether any health signal is configured.
+func (h HealthConfig) Enabled() bool {
+	return len(h.HeartbeatEvents) > 0 || h.ProbeInterval > 0
+}
+
+// RegistrationConfig lets agents mount their routes by announcing a
+// signed manifest on the bus. Agents in Agents stay pinned and cannot be
+// replaced by announcements.
+type RegistrationConfig struct {
+	Enabled        bool              `yaml:"enabled"`
+	AnnounceEvents []string          `yaml:"announce_events"` // default [agent.manifest.announced]
+	WithdrawEvents []string          `yaml:"withdraw_events"` // default [agent.manifest.withdrawn]
+	Keys           []RegistrationKey `yaml:"keys"`
+}
+
+// RegistrationKey allowlists a public key and the agents it may register.
+type RegistrationKey struct {
+	ID            string   `yaml:"id"`
+	PublicKeyFile string   `yaml:"public_key_file"` // PEM Ed25519 or ECDSA
+	Agents        []string `yaml:"agents"`
+}
+
+// CacheConfig bounds the response cache used by actions with a cache TTL.
+type CacheConfig struct {
+	MaxEntries int `yaml:"max_entries"` // default 10000
+}
+
+// WebhooksConfig configures outbound delivery of bus events to external
+// HTTP endpoints.
+type WebhooksConfig struct {
+	Enabled       bool                  `yaml:"enabled"`
+	Queue         string                `yaml:"queue"`      // durable queue, default "agent-gateway.webhooks"
+	StoreDir      string                `yaml:"store_dir"`  // default "data/webhooks"
+	Workers       int                   `yaml:"workers"`    // default 4
+	DedupeTTL     time.Duration         `yaml:"dedupe_ttl"` // how long delivered records are kept, default 24h
+	Subscriptions []WebhookSubscription `yaml:"subscriptions"`
+}
+
+// WebhookSubscription delivers events matching Events to URL, signed with
+// Secret.
+type WebhookSubscription struct {
+	ID             string        `yaml:"id"`
+	Events         []string      `yaml:"events"` // routing key patterns
+	URL            string        `yaml:"url"`
+	Secret         string        `yaml:"secret"`
+	MaxAttempts    int           `yaml:"max_attempts"`    // default 8
+	InitialBackoff time.Duration `yaml:"initial_backoff"` // default 1s
+	MaxBackoff     time.Duration `yaml:"max_backoff"`     // default 10m
+	Timeout        time.Duration `yaml:"timeout"`         // per attempt, default 10s
+}
+
 // InfraConfig holds infrastructure connections.
 type InfraConfig struct {
 	RabbitMQ RabbitMQConfig `yaml:"rabbitmq"`
@@ -29,6 +297,20 @@ type InfraConfig struct {
 type RabbitMQConfig struct {
 	URL      string `yaml:"url"`
 	Exchange string `yaml:"exchange"`
+	// EventSource is the CloudEvent source of events this instance
+	// publishes (default "/agent-gateway").
+	EventSource string `yaml:"event_source"`
+}
+
+// DiscoveryConfig finds agent manifests on disk in addition to those
+// listed under agents. Include and Exclude match manifest names and do not
+// apply to listed agents.
+type DiscoveryConfig struct {
+	ManifestDirs  []string `yaml:"manifest_dirs"`  // searched recursively for ManifestFile
+	ManifestFile  string   `yaml:"manifest_file"`  // default "agent.yaml"
+	ManifestGlobs []string `yaml:"manifest_globs"` // e.g. ../agents/*/agent.yaml
+	Include       []string `yaml:"include"`        // default all
+	Exclude       []string `yaml:"exclude"`
 }
 
 // AgentRef references an agent manifest to load.
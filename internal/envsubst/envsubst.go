@@ -0,0 +1,121 @@
+// Package envsubst expands ${VAR} references in YAML documents from the
+// environment.
+package envsubst
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Lookup returns the value of the environment variable name. When name is
+// unset or empty and name_FILE is set, the named file is read instead, as
+// with secrets mounted into a container; a trailing newline is dropped.
+func Lookup(name string) (string, bool, error) {
+	if v, ok := os.LookupEnv(name); ok && v != "" {
+		return v, true, nil
+	}
+	path, ok := os.LookupEnv(name + "_FILE")
+	if !ok || path == "" {
+		return "", false, nil
+	}
+	v, err := ReadSecret(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+	}
+	return v, true, nil
+}
+
+// ReadSecret reads a secret from a file, dropping a trailing newline.
+func ReadSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Expand replaces ${VAR} and ${VAR:-default} in s. An unset variable
+// without a default expands to the empty string; the default also applies
+// when the variable is empty. $${ is a literal ${.
+func Expand(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i])
+			b.WriteString("{")
+			s = s[i+2:]
+			continue
+		}
+		b.WriteString(s[:i])
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated ${ in %q", s)
+		}
+		ref := s[i+2 : i+end]
+		s = s[i+end+1:]
+
+		name, def, _ := strings.Cut(ref, ":-")
+		if !validName(name) {
+			return "", fmt.Errorf("invalid variable name %q", name)
+		}
+		v, ok, err := Lookup(name)
+		if err != nil {
+			return "", err
+		}
+		if !ok || v == "" {
+			v = def
+		}
+		b.WriteString(v)
+	}
+}
+
+// ExpandNode expands every scalar in a parsed YAML document in place.
+// Expanding after parsing keeps values containing YAML syntax, like a
+// password with ": " or "#", from changing the document's structure.
+func ExpandNode(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		v, err := Expand(n.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n.Line, err)
+		}
+		if v != n.Value {
+			n.Value = v
+			// Re-resolve plain scalars so "${PORT:-8080}" decodes as an int
+			if n.Style == 0 {
+				n.Tag = ""
+			}
+		}
+		return nil
+	}
+	for _, c := range n.Content {
+		if err := ExpandNode(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
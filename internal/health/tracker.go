@@ -0,0 +1,148 @@
+package health
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status is an agent's observed liveness.
+type Status string
+
+const (
+	StatusUp      Status = "up"
+	StatusDown    Status = "down"
+	StatusUnknown Status = "unknown"
+)
+
+// Agent is a snapshot of an agent's health.
+type Agent struct {
+	Name          string     `json:"name"`
+	Status        Status     `json:"status"`
+	LastHeartbeat *time.Time `json:"last_heartbeat,omitempty"`
+	Consumers     *int       `json:"consumers,omitempty"`
+	ProbeError    string     `json:"probe_error,omitempty"`
+}
+
+type agentState struct {
+	lastBeat  time.Time
+	probed    bool
+	consumers int
+	probeErr  string
+}
+
+// Tracker keeps per-agent health from heartbeat events and queue probes.
+// Either negative signal wins, so calls fail fast: an agent is down when
+// its heartbeats have lapsed or its queue has no consumers, even if the
+// other signal looks healthy. It is up when a signal says it is alive and
+// none says it is dead, and unknown before any signal arrives.
+type Tracker struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	agents map[string]*agentState
+}
+
+// NewTracker creates a tracker that considers heartbeats older than
+// timeout lapsed.
+func NewTracker(timeout time.Duration) *Tracker {
+	return &Tracker{
+		timeout: timeout,
+		agents:  make(map[string]*agentState),
+	}
+}
+
+// Track registers an agent so it is reported before any signal arrives.
+func (t *Tracker) Track(name string) {
+	t.mu.Lock()
+	t.state(name)
+	t.mu.Unlock()
+}
+
+// Heartbeat records a heartbeat from an agent.
+func (t *Tracker) Heartbeat(name string, at time.Time) {
+	t.mu.Lock()
+	t.state(name).lastBeat = at
+	t.mu.Unlock()
+}
+
+// Probe records the consumer count of an agent's queue, or the error that
+// prevented reading it.
+func (t *Tracker) Probe(name string, consumers int, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.state(name)
+	if err != nil {
+		s.probed = false
+		s.probeErr = err.Error()
+		return
+	}
+	s.probed = true
+	s.consumers = consumers
+	s.probeErr = ""
+}
+
+// Down reports whether an agent is known to be dead.
+func (t *Tracker) Down(name string) bool {
+	return t.Status(name) == StatusDown
+}
+
+// Status returns an agent's current status.
+func (t *Tracker) Status(name string) Status {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	s, ok := t.agents[name]
+	if !ok {
+		return StatusUnknown
+	}
+	return t.status(s, time.Now())
+}
+
+// Snapshot returns the health of every tracked agent, by name.
+func (t *Tracker) Snapshot() []Agent {
+	now := time.Now()
+
+	t.mu.RLock()
+	list := make([]Agent, 0, len(t.agents))
+	for name, s := range t.agents {
+		a := Agent{Name: name, Status: t.status(s, now), ProbeError: s.probeErr}
+		if !s.lastBeat.IsZero() {
+			beat := s.lastBeat
+			a.LastHeartbeat = &beat
+		}
+		if s.probed {
+			consumers := s.consumers
+			a.Consumers = &consumers
+		}
+		list = append(list, a)
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func (t *Tracker) status(s *agentState, now time.Time) Status {
+	beating := !s.lastBeat.IsZero() && now.Sub(s.lastBeat) <= t.timeout
+	lapsed := !s.lastBeat.IsZero() && !beating
+
+	switch {
+	case lapsed || (s.probed && s.consumers == 0):
+		return StatusDown
+	case beating || s.probed:
+		return StatusUp
+	}
+	return StatusUnknown
+}
+
+// state returns the agent's state, creating it. Called with t.mu held.
+func (t *Tracker) state(name string) *agentState {
+	s, ok := t.agents[name]
+	if !ok {
+		s = &agentState{}
+		t.agents[name] = s
+	}
+	return s
+}
@@ -0,0 +1,54 @@
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTrackerStatusCombinesSignals(t *testing.T) {
+	const timeout = 30 * time.Second
+	fresh := time.Now()
+	stale := time.Now().Add(-2 * timeout)
+
+	tests := []struct {
+		name      string
+		beat      time.Time // zero: no heartbeat yet
+		consumers int       // -1: no probe, -2: probe failed
+		want      Status
+	}{
+		{"no signal", time.Time{}, -1, StatusUnknown},
+		{"probe error only", time.Time{}, -2, StatusUnknown},
+		{"heartbeat only", fresh, -1, StatusUp},
+		{"consumers only", time.Time{}, 2, StatusUp},
+		{"both alive", fresh, 2, StatusUp},
+		{"heartbeat lapsed", stale, -1, StatusDown},
+		{"no consumers", time.Time{}, 0, StatusDown},
+		{"lapsed despite consumers", stale, 2, StatusDown},
+		{"no consumers despite heartbeat", fresh, 0, StatusDown},
+		{"fresh heartbeat after probe error", fresh, -2, StatusUp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTracker(timeout)
+			tr.Track("agent")
+			if !tt.beat.IsZero() {
+				tr.Heartbeat("agent", tt.beat)
+			}
+			switch tt.consumers {
+			case -1:
+			case -2:
+				tr.Probe("agent", 0, errors.New("queue not found"))
+			default:
+				tr.Probe("agent", tt.consumers, nil)
+			}
+
+			if got := tr.Status("agent"); got != tt.want {
+				t.Errorf("Status() = %s, want %s", got, tt.want)
+			}
+			if got := tr.Down("agent"); got != (tt.want == StatusDown) {
+				t.Errorf("Down() = %v", got)
+			}
+		})
+	}
+}
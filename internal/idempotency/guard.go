@@ -0,0 +1,94 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrMismatch is returned when a key is reused with a different request.
+var ErrMismatch = errors.New("idempotency key reused with a different request")
+
+// Guard runs each keyed request once, replays its response for repeats and
+// makes concurrent repeats wait for the in-flight request.
+type Guard struct {
+	store Store
+
+	mu       sync.Mutex
+	inflight map[string]*call
+}
+
+type call struct {
+	fingerprint string
+	done        chan struct{}
+	resp        *Response
+	err         error
+}
+
+// NewGuard creates a guard recording responses in store.
+func NewGuard(store Store) *Guard {
+	return &Guard{
+		store:    store,
+		inflight: make(map[string]*call),
+	}
+}
+
+// Do returns the recorded response for key, waits for an in-flight request
+// with the same key, or runs fn and records its response for ttl. replayed
+// reports whether the response came from an earlier request.
+func (g *Guard) Do(ctx context.Context, key, fingerprint string, ttl time.Duration, fn func() *Response) (resp *Response, replayed bool, err error) {
+	g.mu.Lock()
+	if c, ok := g.inflight[key]; ok {
+		g.mu.Unlock()
+		if c.fingerprint != fingerprint {
+			return nil, false, ErrMismatch
+		}
+		select {
+		case <-c.done:
+			return c.resp, c.err == nil, c.err
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+	c := &call{fingerprint: fingerprint, done: make(chan struct{})}
+	g.inflight[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.inflight, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+
+	// Checked after claiming the key: a finished call records its response
+	// before it leaves the in-flight set.
+	stored, err := g.store.Get(ctx, key)
+	if err != nil {
+		c.err = err
+		return nil, false, err
+	}
+	if stored != nil {
+		if stored.Fingerprint != fingerprint {
+			c.err = ErrMismatch
+			return nil, false, ErrMismatch
+		}
+		c.resp = stored
+		return stored, true, nil
+	}
+
+	resp = fn()
+	resp.Fingerprint = fingerprint
+	c.resp = resp
+
+	// Server errors and responses never written are not recorded so the
+	// client can retry
+	if resp.Status >= 100 && resp.Status < 500 {
+		if err := g.store.Put(context.WithoutCancel(ctx), key, resp, ttl); err != nil {
+			log.Printf("Warning: idempotency store: %v", err)
+		}
+	}
+	return resp, false, nil
+}
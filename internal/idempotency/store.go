@@ -0,0 +1,72 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Response is a recorded response replayed for repeated keys.
+type Response struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+	// Fingerprint identifies the request that produced the response.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// Store persists recorded responses. Get returns nil, nil for unknown or
+// expired keys.
+type Store interface {
+	Get(ctx context.Context, key string) (*Response, error)
+	Put(ctx context.Context, key string, resp *Response, ttl time.Duration) error
+}
+
+// MemoryStore keeps responses in memory, per gateway instance.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	nextSweep time.Time
+}
+
+type memoryEntry struct {
+	resp    *Response
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry)}
+}
+
+// Get returns the response recorded for key.
+func (s *MemoryStore) Get(_ context.Context, key string) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		return nil, nil
+	}
+	return e.resp, nil
+}
+
+// Put records a response for ttl, sweeping expired entries periodically.
+func (s *MemoryStore) Put(_ context.Context, key string, resp *Response, ttl time.Duration) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.After(s.nextSweep) {
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.nextSweep = now.Add(time.Minute)
+	}
+
+	s.entries[key] = memoryEntry{resp: resp, expires: now.Add(ttl)}
+	return nil
+}
@@ -0,0 +1,184 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Errors returned by Acquire when a request is shed.
+var (
+	ErrRejected     = errors.New("concurrency limit reached and queue full")
+	ErrQueueTimeout = errors.New("timed out waiting for a concurrency slot")
+)
+
+// Config limits concurrent calls to one agent.
+type Config struct {
+	MaxInFlight  int           // 0 means unlimited
+	QueueSize    int           // requests allowed to wait for a slot
+	QueueTimeout time.Duration // longest wait for a slot
+
+	// Adaptive enables AIMD: the limit grows by one per window of calls
+	// faster than TargetLatency, and shrinks by 10% on a slower call or
+	// timeout, never below MinInFlight or above MaxInFlight.
+	Adaptive      bool
+	MinInFlight   int
+	TargetLatency time.Duration
+}
+
+// Stats is a snapshot of a limiter.
+type Stats struct {
+	Name          string `json:"name"`
+	Limit         int    `json:"limit"`
+	MaxInFlight   int    `json:"max_in_flight"`
+	InFlight      int    `json:"in_flight"`
+	Queued        int    `json:"queued"`
+	Rejected      uint64 `json:"rejected"`
+	QueueTimeouts uint64 `json:"queue_timeouts"`
+}
+
+// Limiter bounds in-flight calls and queues the excess.
+type Limiter struct {
+	name string
+	cfg  Config
+
+	mu            sync.Mutex
+	limit         float64
+	inFlight      int
+	waiters       []chan struct{}
+	rejected      uint64
+	queueTimeouts uint64
+}
+
+// New creates a limiter starting at the configured maximum.
+func New(name string, cfg Config) *Limiter {
+	if cfg.MinInFlight < 1 {
+		cfg.MinInFlight = 1
+	}
+	return &Limiter{
+		name:  name,
+		cfg:   cfg,
+		limit: float64(cfg.MaxInFlight),
+	}
+}
+
+// Acquire takes a slot, waiting in the queue if needed. The returned
+// release function must be called with the call's latency and whether it
+// timed out.
+func (l *Limiter) Acquire(ctx context.Context) (func(latency time.Duration, timedOut bool), error) {
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	return l.release, nil
+}
+
+func (l *Limiter) acquire(ctx context.Context) error {
+	if l.cfg.MaxInFlight == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	if l.inFlight < int(l.limit) && len(l.waiters) == 0 {
+		l.inFlight++
+		l.mu.Unlock()
+		return nil
+	}
+	if len(l.waiters) >= l.cfg.QueueSize {
+		l.rejected++
+		l.mu.Unlock()
+		return ErrRejected
+	}
+	ch := make(chan struct{})
+	l.waiters = append(l.waiters, ch)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.cfg.QueueTimeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-ch:
+		return nil
+	case <-timer.C:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, w := range l.waiters {
+		if w == ch {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			if err == ErrQueueTimeout {
+				l.queueTimeouts++
+			}
+			return err
+		}
+	}
+	// The slot was handed over as we gave up
+	return nil
+}
+
+func (l *Limiter) release(latency time.Duration, timedOut bool) {
+	if l.cfg.MaxInFlight == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cfg.Adaptive {
+		l.adapt(latency, timedOut)
+	}
+	l.free()
+}
+
+// abandon frees a slot whose call never ran, leaving the limit alone.
+func (l *Limiter) abandon() {
+	if l.cfg.MaxInFlight == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.free()
+}
+
+// free gives up a slot. Called with l.mu held.
+func (l *Limiter) free() {
+	l.inFlight--
+
+	// Hand freed slots to waiters in arrival order
+	for len(l.waiters) > 0 && l.inFlight < int(l.limit) {
+		ch := l.waiters[0]
+		l.waiters = l.waiters[1:]
+		l.inFlight++
+		close(ch)
+	}
+}
+
+// adapt applies AIMD to the limit. Called with l.mu held.
+func (l *Limiter) adapt(latency time.Duration, timedOut bool) {
+	if timedOut || (l.cfg.TargetLatency > 0 && latency > l.cfg.TargetLatency) {
+		l.limit = max(l.limit*0.9, float64(l.cfg.MinInFlight))
+		return
+	}
+	l.limit = min(l.limit+1/l.limit, float64(l.cfg.MaxInFlight))
+}
+
+// Stats returns a snapshot of the limiter.
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return Stats{
+		Name:          l.name,
+		Limit:         int(l.limit),
+		MaxInFlight:   l.cfg.MaxInFlight,
+		InFlight:      l.inFlight,
+		Queued:        len(l.waiters),
+		Rejected:      l.rejected,
+		QueueTimeouts: l.queueTimeouts,
+	}
+}
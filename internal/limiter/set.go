@@ -0,0 +1,76 @@
+package limiter
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Set holds a global limiter and one limiter per agent.
+type Set struct {
+	global    *Limiter
+	defaults  Config
+	overrides map[string]Config
+
+	mu     sync.Mutex
+	agents map[string]*Limiter
+}
+
+// NewSet creates limiters from the global limit, the per-agent defaults
+// and per-agent overrides keyed by agent name.
+func NewSet(global Config, defaults Config, overrides map[string]Config) *Set {
+	return &Set{
+		global:    New("global", global),
+		defaults:  defaults,
+		overrides: overrides,
+		agents:    make(map[string]*Limiter),
+	}
+}
+
+// Acquire takes a slot for agent and then a global slot.
+func (s *Set) Acquire(ctx context.Context, agent string) (func(latency time.Duration, timedOut bool), error) {
+	l := s.agent(agent)
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.global.acquire(ctx); err != nil {
+		// No call was made, so the agent's adaptive limit must not move
+		l.abandon()
+		return nil, err
+	}
+
+	return func(latency time.Duration, timedOut bool) {
+		s.global.release(latency, timedOut)
+		l.release(latency, timedOut)
+	}, nil
+}
+
+func (s *Set) agent(name string) *Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.agents[name]
+	if !ok {
+		cfg, ok := s.overrides[name]
+		if !ok {
+			cfg = s.defaults
+		}
+		l = New(name, cfg)
+		s.agents[name] = l
+	}
+	return l
+}
+
+// Stats returns the global limiter's stats and each agent's, by name.
+func (s *Set) Stats() (Stats, []Stats) {
+	s.mu.Lock()
+	agents := make([]Stats, 0, len(s.agents))
+	for _, l := range s.agents {
+		agents = append(agents, l.Stats())
+	}
+	s.mu.Unlock()
+
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Name < agents[j].Name })
+	return s.global.Stats(), agents
+}
@@ -1,9 +1,13 @@
 package manifest
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 )
 
 // Loader loads manifests from file paths.
@@ -16,12 +20,17 @@ func NewLoader(basePath string) *Loader {
 	return &Loader{basePath: basePath}
 }
 
+// Resolve returns path joined to the base path unless it is absolute.
+func (l *Loader) Resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(l.basePath, path)
+}
+
 // Load reads a manifest from a file path.
 func (l *Loader) Load(path string) (*Manifest, error) {
-	fullPath := path
-	if !filepath.IsAbs(path) {
-		fullPath = filepath.Join(l.basePath, path)
-	}
+	fullPath := l.Resolve(path)
 
 	data, err := os.ReadFile(fullPath)
 	if err != nil {
@@ -51,3 +60,55 @@ func (l *Loader) LoadAll(paths []string) ([]Manifest, error) {
 	}
 	return manifests, nil
 }
+
+// Discover returns the manifest files named file under dirs, searched
+// recursively, plus those matching globs. Paths are resolved, deduplicated
+// and sorted so load order does not depend on the filesystem. Hidden
+// directories are skipped. A directory or pattern that cannot be read is
+// reported in the error while the others are still returned.
+func (l *Loader) Discover(dirs []string, file string, globs []string) ([]string, error) {
+	found := make(map[string]bool)
+	var errs []error
+
+	for _, dir := range dirs {
+		root := l.Resolve(dir)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.Name() == file {
+				found[path] = true
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("scan %s: %w", root, err))
+		}
+	}
+
+	for _, pattern := range globs {
+		matches, err := filepath.Glob(l.Resolve(pattern))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("glob %s: %w", pattern, err))
+			continue
+		}
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.Mode().IsRegular() {
+				found[m] = true
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(found))
+	for p := range found {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths, errors.Join(errs...)
+}
@@ -2,19 +2,44 @@ package manifest
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/jhaveripatric/agent-gateway/internal/envsubst"
+	"github.com/jhaveripatric/agent-gateway/internal/yamlcheck"
 	"gopkg.in/yaml.v3"
 )
 
-// Parse converts YAML data to a Manifest.
+// Parse converts YAML data to a Manifest, expanding ${VAR} references
+// from the environment.
 func Parse(data []byte) (*Manifest, error) {
-	var m Manifest
-	if err := yaml.Unmarshal(data, &m); err != nil {
+	return parse(data, true)
+}
+
+// ParseLiteral converts YAML data to a Manifest without environment
+// expansion, for manifests from outside the host that must not read its
+// environment.
+func ParseLiteral(data []byte) (*Manifest, error) {
+	return parse(data, false)
+}
+
+func parse(data []byte, expand bool) (*Manifest, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
 		return nil, fmt.Errorf("unmarshal manifest: %w", err)
 	}
+	if expand {
+		if err := envsubst.ExpandNode(&doc); err != nil {
+			return nil, fmt.Errorf("expand manifest: %w", err)
+		}
+	}
 
-	if err := validate(&m); err != nil {
+	var m Manifest
+	report := yamlcheck.NewReport(&doc)
+	report.Decode(&doc, &m)
+	validate(&m, report)
+	if err := report.Err(); err != nil {
 		return nil, err
 	}
 
@@ -22,9 +47,145 @@ func Parse(data []byte) (*Manifest, error) {
 	return &m, nil
 }
 
-func validate(m *Manifest) error {
+// authModes lists the values accepted in an action's auth field. Modes may
+// be combined with "|", e.g. "bearer|api_key"; "optional" stands alone.
+var authModes = map[string]bool{
+	"none":     true,
+	"bearer":   true,
+	"mtls":     true,
+	"api_key":  true,
+	"optional": true,
+}
+
+// validate records every problem in r, against the key it concerns.
+func validate(m *Manifest, r *yamlcheck.Report) {
 	if m.Name == "" {
-		return fmt.Errorf("manifest name is required")
+		r.Addf("name", "manifest name is required")
+	}
+	if m.ContentMode != "" && m.ContentMode != "structured" && m.ContentMode != "binary" {
+		r.Addf("content_mode", "invalid content_mode %q", m.ContentMode)
+	}
+	for i, a := range m.Actions {
+		if err := validateAction(a); err != nil {
+			r.Add(fmt.Sprintf("actions[%d]", i), fmt.Errorf("action %s: %w", a.Name, err))
+		}
+	}
+}
+
+func validateAction(a Action) error {
+	if a.Auth != "" {
+		modes := strings.Split(a.Auth, "|")
+		for _, mode := range modes {
+			if !authModes[mode] {
+				return fmt.Errorf("unknown auth mode %q", mode)
+			}
+			if (mode == "optional" || mode == "none") && len(modes) > 1 {
+				return fmt.Errorf("auth mode %q cannot be combined", mode)
+			}
+		}
+	}
+	if err := validateType(a); err != nil {
+		return err
+	}
+	if a.ScopeMatch != "" && a.ScopeMatch != "all" && a.ScopeMatch != "any" {
+		return fmt.Errorf("invalid scope_match %q", a.ScopeMatch)
+	}
+	if err := validateCache(a); err != nil {
+		return err
+	}
+	if err := validateRetry(a); err != nil {
+		return err
+	}
+	switch a.Idempotency {
+	case "", "optional", "required", "off":
+	default:
+		return fmt.Errorf("invalid idempotency %q", a.Idempotency)
+	}
+	return nil
+}
+
+// webhookProfiles lists the signature schemes for webhook actions.
+var webhookProfiles = map[string]bool{
+	"":       true,
+	"hmac":   true,
+	"github": true,
+	"stripe": true,
+	"slack":  true,
+}
+
+func validateType(a Action) error {
+	switch a.Type {
+	case "", "rpc":
+		return nil
+	case "webhook":
+	default:
+		return fmt.Errorf("unknown type %q", a.Type)
+	}
+
+	// The provider's signature is the only credential
+	if a.Auth != "" && a.Auth != "none" {
+		return fmt.Errorf("webhook actions authenticate by signature, not auth %q", a.Auth)
+	}
+	if len(a.Scopes) > 0 {
+		return fmt.Errorf("webhook actions cannot require scopes")
+	}
+	if !webhookProfiles[a.Webhook.Profile] {
+		return fmt.Errorf("unknown webhook profile %q", a.Webhook.Profile)
+	}
+	if a.Webhook.Secret == "" {
+		return fmt.Errorf("webhook.secret is required")
+	}
+	if a.Request.Event == "" {
+		return fmt.Errorf("request.event is required")
+	}
+	return nil
+}
+
+func validateCache(a Action) error {
+	c := a.Cache
+	if c.TTL == 0 {
+		if c.StaleWhileRevalidate > 0 || len(c.VaryBy) > 0 || len(c.InvalidateOn) > 0 {
+			return fmt.Errorf("cache.ttl is required")
+		}
+		return nil
+	}
+	if c.TTL < 0 || c.StaleWhileRevalidate < 0 {
+		return fmt.Errorf("cache durations must be positive")
+	}
+	if a.HTTP.Method != "" && a.HTTP.Method != "GET" {
+		return fmt.Errorf("only GET actions can be cached")
+	}
+	for _, v := range c.VaryBy {
+		if v != "user" && v != "query" && v != "params" {
+			return fmt.Errorf("unknown cache vary_by %q", v)
+		}
+	}
+	// The fill runs as the first caller, so its response is theirs alone
+	if len(c.VaryBy) > 0 && !slices.Contains(c.VaryBy, "user") && a.Auth != "" && a.Auth != "none" {
+		return fmt.Errorf("cache.vary_by must include user for authenticated actions")
+	}
+	return nil
+}
+
+func validateRetry(a Action) error {
+	rc := a.Retry
+	if rc.MaxAttempts <= 1 {
+		return nil
+	}
+	// Retrying a POST is only safe when agents can deduplicate by key
+	if (a.HTTP.Method == "" || a.HTTP.Method == "POST") && a.Idempotency != "required" {
+		return fmt.Errorf("retry on POST requires idempotency: required")
+	}
+	if len(rc.On) == 0 && len(rc.Events) == 0 {
+		return fmt.Errorf("retry needs at least one condition in on or events")
+	}
+	for _, cond := range rc.On {
+		if cond != "publish_error" && cond != "timeout" {
+			return fmt.Errorf("unknown retry condition %q", cond)
+		}
+	}
+	if rc.Backoff < 0 || rc.MaxBackoff < 0 || rc.AttemptTimeout < 0 {
+		return fmt.Errorf("retry durations must be positive")
 	}
 	return nil
 }
@@ -40,5 +201,34 @@ func setDefaults(m *Manifest) {
 		if m.Actions[i].HTTP.Method == "" {
 			m.Actions[i].HTTP.Method = "POST"
 		}
+		if m.Actions[i].Type == "" {
+			m.Actions[i].Type = "rpc"
+		}
+		if m.Actions[i].Type == "webhook" && m.Actions[i].Webhook.DedupeTTL == 0 {
+			m.Actions[i].Webhook.DedupeTTL = 24 * time.Hour
+		}
+		if m.Actions[i].Cache.TTL > 0 && m.Actions[i].Cache.VaryBy == nil {
+			m.Actions[i].Cache.VaryBy = []string{"user", "query", "params"}
+		}
+		if r := &m.Actions[i].Retry; r.MaxAttempts > 1 {
+			if r.Backoff == 0 {
+				r.Backoff = 100 * time.Millisecond
+			}
+			if r.MaxBackoff == 0 {
+				r.MaxBackoff = 2 * time.Second
+			}
+			if r.AttemptTimeout == 0 && slices.Contains(r.On, "timeout") {
+				r.AttemptTimeout = m.Actions[i].Timeout / time.Duration(r.MaxAttempts)
+			}
+		}
+		if m.Actions[i].Idempotency == "" {
+			m.Actions[i].Idempotency = "optional"
+		}
+		if m.Actions[i].ScopeMatch == "" {
+			m.Actions[i].ScopeMatch = "all"
+		}
+		if m.Actions[i].ForwardClaims == nil {
+			m.Actions[i].ForwardClaims = m.ForwardClaims
+		}
 	}
 }
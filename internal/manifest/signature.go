@@ -0,0 +1,59 @@
+package manifest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrBadSignature is returned when a signed manifest fails verification.
+var ErrBadSignature = errors.New("invalid manifest signature")
+
+// LoadPublicKey reads a PEM-encoded Ed25519 or ECDSA public key used to
+// verify signed manifest announcements.
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	switch pub.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey:
+		return pub, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported key type %T", path, pub)
+}
+
+// VerifySignature checks an Ed25519 signature of data, or an ASN.1 ECDSA
+// signature of its SHA-256 digest.
+func VerifySignature(pub crypto.PublicKey, data, sig []byte) error {
+	switch key := pub.(type) {
+	case ed25519.PublicKey:
+		if ed25519.Verify(key, data, sig) {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		if ecdsa.VerifyASN1(key, digest[:], sig) {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unsupported key type %T", pub)
+	}
+	return ErrBadSignature
+}
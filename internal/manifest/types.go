@@ -4,12 +4,20 @@ import "time"
 
 // Manifest represents an agent's capabilities and routes.
 type Manifest struct {
-	Name         string     `yaml:"name"`
-	Version      string     `yaml:"version"`
-	Description  string     `yaml:"description"`
-	JWT          *JWTConfig `yaml:"jwt,omitempty"`
-	Actions      []Action   `yaml:"actions"`
-	ManifestPath string     `yaml:"-"` // Set by loader, not from YAML
+	Name        string     `yaml:"name"`
+	Version     string     `yaml:"version"`
+	Description string     `yaml:"description"`
+	JWT         *JWTConfig `yaml:"jwt,omitempty"`
+	// ContentMode is how the agent consumes events: "structured" (default)
+	// or "binary", with attributes in AMQP headers.
+	ContentMode string `yaml:"content_mode"`
+	// Queue is the agent's request queue, probed for consumers when
+	// health probing is enabled.
+	Queue string `yaml:"queue"`
+	// ForwardClaims is the default claim allowlist for actions.
+	ForwardClaims []string `yaml:"forward_claims"`
+	Actions       []Action `yaml:"actions"`
+	ManifestPath  string   `yaml:"-"` // Set by loader, not from YAML
 }
 
 // JWTConfig holds JWT validation settings.
@@ -23,15 +31,87 @@ type JWTConfig struct {
 
 // Action represents a single API action.
 type Action struct {
-	Name        string         `yaml:"name"`
-	Description string         `yaml:"description"`
-	HTTP        HTTPConfig     `yaml:"http"`
-	Auth        string         `yaml:"auth"`
-	Permission  string         `yaml:"permission"`
-	RateLimit   string         `yaml:"rate_limit"`
-	Timeout     time.Duration  `yaml:"timeout"`
-	Request     RequestConfig  `yaml:"request"`
-	Response    ResponseConfig `yaml:"response"`
+	Name        string     `yaml:"name"`
+	Description string     `yaml:"description"`
+	Type        string     `yaml:"type"` // "rpc" (default) or "webhook"
+	HTTP        HTTPConfig `yaml:"http"`
+	Auth        string     `yaml:"auth"`
+	Permission  string     `yaml:"permission"`
+	Scopes      []string   `yaml:"scopes"`
+	ScopeMatch  string     `yaml:"scope_match"` // "all" (default) or "any"
+	// ForwardClaims lists extra token claims copied into _auth.claims.
+	ForwardClaims []string `yaml:"forward_claims"`
+	RateLimit     string   `yaml:"rate_limit"`
+	// Idempotency is "optional" (default), "required" or "off" for
+	// Idempotency-Key handling on POST, PUT and DELETE.
+	Idempotency  string         `yaml:"idempotency"`
+	Timeout      time.Duration  `yaml:"timeout"`
+	MaxBodyBytes int64          `yaml:"max_body_bytes"` // 0 uses the gateway default
+	Request      RequestConfig  `yaml:"request"`
+	Response     ResponseConfig `yaml:"response"`
+	Session      SessionConfig  `yaml:"session"`
+	Cache        CacheConfig    `yaml:"cache"`
+	Retry        RetryConfig    `yaml:"retry"`
+	Webhook      WebhookConfig  `yaml:"webhook"`
+}
+
+// RetryConfig retries transient failures of an action's call within the
+// action timeout. POST actions must require idempotency keys to retry.
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"max_attempts"` // including the first; 0 or 1 disables
+	Backoff     time.Duration `yaml:"backoff"`      // first delay, doubled per retry, default 100ms
+	MaxBackoff  time.Duration `yaml:"max_backoff"`  // default 2s
+	// AttemptTimeout limits each attempt. Defaults to the action timeout
+	// divided by MaxAttempts when retrying on timeout.
+	AttemptTimeout time.Duration `yaml:"attempt_timeout"`
+	// On lists retryable conditions: "publish_error" and "timeout".
+	On []string `yaml:"on"`
+	// Events lists failure event types that are retried.
+	Events []string `yaml:"events"`
+}
+
+// CacheConfig caches successful responses of a GET action. Concurrent
+// identical requests share one agent call.
+type CacheConfig struct {
+	TTL time.Duration `yaml:"ttl"` // 0 disables caching
+	// StaleWhileRevalidate serves expired entries for this long while a
+	// background call refreshes them.
+	StaleWhileRevalidate time.Duration `yaml:"stale_while_revalidate"`
+	// VaryBy lists "user", "query" and "params"; all three by default.
+	VaryBy []string `yaml:"vary_by"`
+	// InvalidateOn lists routing key patterns whose events clear the cache.
+	InvalidateOn []string `yaml:"invalidate_on"`
+}
+
+// WebhookConfig verifies third-party webhooks for actions of type
+// "webhook". Verified payloads are published without waiting for a reply.
+type WebhookConfig struct {
+	// Profile is "hmac" (default), "github", "stripe" or "slack". Profiles
+	// preset the header, prefix, timestamp and delivery ID fields below.
+	// The delivery ID comes from signed content: IDField in the body, or
+	// the signature itself.
+	Profile         string        `yaml:"profile"`
+	Secret          string        `yaml:"secret"`
+	Header          string        `yaml:"header"`
+	Prefix          string        `yaml:"prefix"`
+	Encoding        string        `yaml:"encoding"` // "hex" (default) or "base64"
+	TimestampHeader string        `yaml:"timestamp_header"`
+	Tolerance       time.Duration `yaml:"tolerance"` // default 5m
+	IDField         string        `yaml:"id_field"`
+	DedupeTTL       time.Duration `yaml:"dedupe_ttl"` // default 24h
+	// ForwardHeaders are copied into the event, e.g. X-GitHub-Event.
+	ForwardHeaders []string `yaml:"forward_headers"`
+}
+
+// SessionConfig controls the browser session cookie for an action.
+type SessionConfig struct {
+	// SetFrom names the success response field holding the JWT to store
+	// in the session cookie (e.g. on login).
+	SetFrom string `yaml:"set_from"`
+	// Strip removes the SetFrom field from the response body.
+	Strip bool `yaml:"strip"`
+	// Clear expires the session cookie on a 2xx response (e.g. on logout).
+	Clear bool `yaml:"clear"`
 }
 
 // HTTPConfig defines HTTP method and path.
@@ -42,8 +122,9 @@ type HTTPConfig struct {
 
 // RequestConfig defines the request event and schema.
 type RequestConfig struct {
-	Event  string         `yaml:"event"`
-	Schema map[string]any `yaml:"schema"`
+	Event      string         `yaml:"event"`
+	DataSchema string         `yaml:"dataschema"` // URI sent as the CloudEvent dataschema
+	Schema     map[string]any `yaml:"schema"`
 }
 
 // ResponseConfig defines response mappings.
@@ -4,13 +4,21 @@ import (
 	"github.com/go-chi/cors"
 )
 
-// CORSOptions returns configured CORS options.
-func CORSOptions(allowedOrigins []string) cors.Options {
+// CORSOptions returns configured CORS options. Extra headers, such as the
+// API key header, are added to the allowed request headers.
+func CORSOptions(allowedOrigins []string, extraHeaders ...string) cors.Options {
+	headers := []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"}
+	for _, h := range extraHeaders {
+		if h != "" {
+			headers = append(headers, h)
+		}
+	}
+
 	return cors.Options{
 		AllowedOrigins:   allowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID"},
-		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowedHeaders:   headers,
+		ExposedHeaders:   []string{"X-Request-ID", "Idempotent-Replayed"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}
@@ -32,7 +32,26 @@ func Logger(next http.Handler) http.Handler {
 		next.ServeHTTP(rw, r)
 
 		reqID := GetRequestID(r.Context())
-		log.Printf("[%s] %s %s %d %d %s",
-			reqID[:8], r.Method, r.URL.Path, rw.status, rw.size, time.Since(start))
+		if len(reqID) > 8 {
+			reqID = reqID[:8]
+		}
+
+		clientIP := GetClientIP(r.Context())
+		if clientIP == "" {
+			clientIP = r.RemoteAddr
+		}
+
+		log.Printf("[%s] %s %s %s://%s%s %d %d %s",
+			reqID, clientIP, r.Method, schemeOf(r), r.Host, r.URL.Path, rw.status, rw.size, time.Since(start))
 	})
 }
+
+func schemeOf(r *http.Request) string {
+	if scheme := GetScheme(r.Context()); scheme != "" {
+		return scheme
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate is a token bucket refill rate with a burst equal to the count.
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+// ParseRate parses rates such as "100/s", "600/m" or "1000/h".
+func ParseRate(s string) (Rate, error) {
+	count, unit, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("invalid rate %q: want N/s, N/m or N/h", s)
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil || n <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: count must be a positive integer", s)
+	}
+
+	var per time.Duration
+	switch unit {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Rate{}, fmt.Errorf("invalid rate %q: unit must be s, m or h", s)
+	}
+
+	return Rate{PerSecond: float64(n) / per.Seconds(), Burst: n}, nil
+}
+
+// RateLimiter applies a token bucket per key.
+type RateLimiter struct {
+	rate Rate
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a limiter with the given rate for every key.
+func NewRateLimiter(rate Rate) *RateLimiter {
+	return &RateLimiter{
+		rate:    rate,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token for key. When none is available it returns false and
+// how long until the next token.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.rate.Burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(l.rate.Burst), b.tokens+now.Sub(b.last).Seconds()*l.rate.PerSecond)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate.PerSecond * float64(time.Second))
+	return false, wait
+}
@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+const (
+	clientIPKey ctxKey = "client_ip"
+	schemeKey   ctxKey = "scheme"
+)
+
+// ParseTrustedProxies parses CIDRs or bare IPs into prefixes.
+func ParseTrustedProxies(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// RealIP resolves the client IP and scheme. Forwarding headers are only
+// honoured when the immediate peer is one of the trusted proxies.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, scheme := resolveClient(r, trusted)
+
+			ctx := context.WithValue(r.Context(), clientIPKey, ip)
+			ctx = context.WithValue(ctx, schemeKey, scheme)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetClientIP returns the resolved client IP from context.
+func GetClientIP(ctx context.Context) string {
+	if ip, ok := ctx.Value(clientIPKey).(string); ok {
+		return ip
+	}
+	return ""
+}
+
+// GetScheme returns the resolved request scheme from context.
+func GetScheme(ctx context.Context) string {
+	if scheme, ok := ctx.Value(schemeKey).(string); ok {
+		return scheme
+	}
+	return ""
+}
+
+func resolveClient(r *http.Request, trusted []netip.Prefix) (string, string) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	peer, ok := parseAddr(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr, scheme
+	}
+	if !isTrusted(peer, trusted) {
+		return peer.String(), scheme
+	}
+
+	// Preference: RFC 7239 Forwarded, then X-Forwarded-For, then X-Real-IP
+	var hops []hop
+	var proto string
+	if fwd := r.Header.Values("Forwarded"); len(fwd) > 0 {
+		hops = parseForwarded(strings.Join(fwd, ","))
+	} else if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops = parseForwardedFor(strings.Join(xff, ","))
+		proto = r.Header.Get("X-Forwarded-Proto")
+	} else if real := r.Header.Get("X-Real-IP"); real != "" {
+		hops = parseForwardedFor(real)
+		proto = r.Header.Get("X-Forwarded-Proto")
+	}
+
+	// Walk right to left: the first untrusted hop is the client. Anything
+	// further left was supplied by the client and cannot be trusted. Each
+	// element visited was added by a proxy already found trusted, so its
+	// proto is too; the last one seen comes from the client-facing proxy.
+	client := peer.String()
+	for i := len(hops) - 1; i >= 0; i-- {
+		h := hops[i]
+		if h.proto != "" {
+			proto = h.proto
+		}
+		if !h.addr.IsValid() {
+			// A trusted proxy hid the next hop; nothing left of it is known
+			client = h.id
+			break
+		}
+		client = h.addr.String()
+		if !isTrusted(h.addr, trusted) {
+			break
+		}
+	}
+
+	proto = strings.ToLower(strings.TrimSpace(proto))
+	if proto == "http" || proto == "https" {
+		scheme = proto
+	}
+
+	return client, scheme
+}
+
+// hop is one forwarding entry. addr is invalid for "unknown", obfuscated
+// and unparseable identifiers, which are kept so the walk stops at them.
+type hop struct {
+	addr  netip.Addr
+	id    string // node identifier as sent
+	proto string // proto= of a Forwarded element
+}
+
+func newHop(id string) hop {
+	h := hop{id: id}
+	if addr, ok := parseAddr(id); ok {
+		h.addr = addr
+	}
+	if h.id == "" {
+		h.id = "unknown"
+	}
+	return h
+}
+
+// parseForwarded extracts one hop per element of an RFC 7239 Forwarded
+// header value, with that element's proto=.
+func parseForwarded(value string) []hop {
+	var hops []hop
+
+	for _, element := range strings.Split(value, ",") {
+		var id, proto string
+		for _, pair := range strings.Split(element, ";") {
+			key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				continue
+			}
+			val = strings.Trim(strings.TrimSpace(val), `"`)
+
+			switch strings.ToLower(key) {
+			case "for":
+				id = val
+			case "proto":
+				proto = val
+			}
+		}
+		h := newHop(id)
+		h.proto = proto
+		hops = append(hops, h)
+	}
+
+	return hops
+}
+
+func parseForwardedFor(value string) []hop {
+	var hops []hop
+	for _, part := range strings.Split(value, ",") {
+		hops = append(hops, newHop(strings.TrimSpace(part)))
+	}
+	return hops
+}
+
+// parseAddr parses an IP with optional port and IPv6 brackets.
+func parseAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
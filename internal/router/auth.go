@@ -0,0 +1,192 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jhaveripatric/agent-gateway/internal/auth"
+	"github.com/jhaveripatric/agent-gateway/internal/manifest"
+)
+
+// authError is an authentication failure. Its message is safe to return
+// to the client; the wrapped error is only logged.
+type authError struct {
+	message string
+	err     error
+}
+
+func (e *authError) Error() string { return e.message }
+
+// authenticate resolves the caller identity required by the action's auth
+// mode. It returns nil claims for routes that need no authentication.
+//
+// Combined modes such as "bearer|api_key" use the first mode for which the
+// request carries credentials. Under "optional", any presented credentials
+// must be valid but anonymous requests pass with nil claims.
+func (b *Builder) authenticate(r *http.Request, action manifest.Action) (*auth.Claims, error) {
+	if action.Auth == "" || action.Auth == "none" {
+		return nil, nil
+	}
+
+	if action.Auth == "optional" {
+		for _, mode := range []string{"bearer", "api_key"} {
+			if b.hasCredentials(r, mode) {
+				return b.authenticateWith(r, mode)
+			}
+		}
+		return nil, nil
+	}
+
+	modes := strings.Split(action.Auth, "|")
+	for _, mode := range modes {
+		if b.hasCredentials(r, mode) {
+			return b.authenticateWith(r, mode)
+		}
+	}
+
+	// Nothing presented: report what the primary mode expected
+	return b.authenticateWith(r, modes[0])
+}
+
+func (b *Builder) hasCredentials(r *http.Request, mode string) bool {
+	switch mode {
+	case "bearer":
+		return r.Header.Get("Authorization") != "" || b.hasSessionCookie(r)
+	case "mtls":
+		return hasClientCert(r)
+	case "api_key":
+		return b.cfg.APIKeys != nil && b.cfg.APIKeys.HasKey(r)
+	}
+	return false
+}
+
+func (b *Builder) authenticateWith(r *http.Request, mode string) (*auth.Claims, error) {
+	switch mode {
+	case "bearer":
+		return b.verifyBearer(r)
+
+	case "mtls":
+		return b.verifyClientCert(r)
+
+	case "api_key":
+		if b.cfg.APIKeys == nil {
+			return nil, &authError{message: "Missing or invalid API key"}
+		}
+		claims, err := b.cfg.APIKeys.Authenticate(r)
+		if err != nil {
+			return nil, &authError{message: "Missing or invalid API key", err: err}
+		}
+		return claims, nil
+	}
+
+	return nil, nil
+}
+
+func (b *Builder) verifyBearer(r *http.Request) (*auth.Claims, error) {
+	var cookieName string
+	if b.cfg.Session != nil {
+		cookieName = b.cfg.Session.Name
+	}
+
+	token, fromCookie, err := auth.ExtractToken(r, cookieName)
+	if err != nil {
+		return nil, &authError{message: "Missing or invalid token"}
+	}
+
+	// Browsers attach cookies automatically, so cookie-authenticated
+	// unsafe requests must prove they came from our origin.
+	if fromCookie {
+		if err := b.cfg.Session.VerifyCSRF(r); err != nil {
+			return nil, &authError{message: "Invalid CSRF token", err: err}
+		}
+	}
+
+	claims, err := b.verifiers.Verify(r.Context(), token)
+	if err != nil {
+		return nil, &authError{message: "Invalid token", err: err}
+	}
+	if b.cfg.Revocations != nil && b.cfg.Revocations.IsRevoked(claims) {
+		return nil, &authError{message: "Token revoked", err: fmt.Errorf("revoked token for user %s", claims.UserID)}
+	}
+	if fromCookie {
+		// Verifiers may return cached claims shared with other requests
+		session := *claims
+		session.Method = "session"
+		claims = &session
+	}
+	return claims, nil
+}
+
+func (b *Builder) hasSessionCookie(r *http.Request) bool {
+	if b.cfg.Session == nil {
+		return false
+	}
+	c, err := r.Cookie(b.cfg.Session.Name)
+	return err == nil && c.Value != ""
+}
+
+func (b *Builder) verifyClientCert(r *http.Request) (*auth.Claims, error) {
+	claims, err := auth.ClientCertClaims(r.TLS)
+	if err != nil {
+		return nil, &authError{message: "Client certificate required", err: err}
+	}
+	return claims, nil
+}
+
+func hasClientCert(r *http.Request) bool {
+	return r.TLS != nil && len(r.TLS.VerifiedChains) > 0
+}
+
+// hasScopes reports whether granted satisfies required under match
+// ("all" or "any").
+func hasScopes(granted, required []string, match string) bool {
+	set := make(map[string]bool, len(granted))
+	for _, s := range granted {
+		set[s] = true
+	}
+
+	for _, s := range required {
+		if set[s] && match == "any" {
+			return true
+		}
+		if !set[s] && match != "any" {
+			return false
+		}
+	}
+	return match != "any"
+}
+
+// authData builds the _auth event attribute from claims, copying any
+// allowlisted custom claims under "claims".
+func authData(claims *auth.Claims, forward []string) map[string]any {
+	data := map[string]any{
+		"user_id":  claims.UserID,
+		"username": claims.Username,
+		"roles":    claims.Roles,
+	}
+	if scopes := claims.Scopes(); len(scopes) > 0 {
+		data["scopes"] = scopes
+	}
+	if len(forward) > 0 {
+		extra := make(map[string]any, len(forward))
+		for _, name := range forward {
+			if v, ok := claims.Claim(name); ok {
+				extra[name] = v
+			}
+		}
+		if len(extra) > 0 {
+			data["claims"] = extra
+		}
+	}
+	if claims.Method != "" {
+		data["method"] = claims.Method
+	}
+	switch claims.Method {
+	case "mtls":
+		data["subject"] = claims.Subject
+	case "api_key":
+		data["key_id"] = claims.Subject
+	}
+	return data
+}
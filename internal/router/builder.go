@@ -1,35 +1,96 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jhaveripatric/agent-gateway/internal/auth"
+	"github.com/jhaveripatric/agent-gateway/internal/cache"
+	"github.com/jhaveripatric/agent-gateway/internal/health"
+	"github.com/jhaveripatric/agent-gateway/internal/idempotency"
+	"github.com/jhaveripatric/agent-gateway/internal/limiter"
 	"github.com/jhaveripatric/agent-gateway/internal/manifest"
 	"github.com/jhaveripatric/agent-gateway/internal/middleware"
 	"github.com/jhaveripatric/agent-gateway/internal/rpc"
+	"github.com/jhaveripatric/agent-gateway/internal/tenant"
+	"github.com/jhaveripatric/agent-gateway/internal/webhook"
 )
 
 // Builder creates routes from agent manifests.
 type Builder struct {
-	rpc         *rpc.Client
-	jwtVerifier *auth.JWTVerifier
+	rpc       *rpc.Client
+	verifiers *auth.VerifierSet
+	cfg       Config
+	routes    []Route
 }
 
-// NewBuilder creates a route builder with RPC client and JWT verifier.
-func NewBuilder(rpcClient *rpc.Client, jwtVerifier *auth.JWTVerifier) *Builder {
+// Config holds gateway-wide settings applied to agent routes.
+type Config struct {
+	// MaxBodyBytes limits request bodies for actions without their own limit.
+	MaxBodyBytes int64
+
+	// MaxEventBytes limits published events. 0 allows the body limit
+	// plus eventOverhead.
+	MaxEventBytes int64
+
+	// APIKeys validates auth: api_key actions. Nil rejects all API keys.
+	APIKeys *auth.APIKeyStore
+
+	// Session enables cookie-based sessions for browser clients.
+	Session *auth.SessionCookie
+
+	// Revocations rejects revoked JWTs. Nil disables the check.
+	Revocations *auth.RevocationList
+
+	// Tenants resolves and validates tenants. Nil disables tenancy.
+	Tenants *tenant.Registry
+
+	// Idempotency replays responses for requests repeating an
+	// IdempotencyHeader key within IdempotencyTTL. Nil disables it.
+	Idempotency       *idempotency.Guard
+	IdempotencyHeader string
+	IdempotencyTTL    time.Duration
+
+	// Cache holds responses of actions with a cache TTL, and Coalesce
+	// collapses their concurrent misses. Nil Cache disables caching.
+	Cache    *cache.Cache
+	Coalesce *cache.Group
+
+	// Limits bounds concurrent calls per agent and overall. Nil disables
+	// load shedding. RetryAfter is sent with shed requests.
+	Limits     *limiter.Set
+	RetryAfter time.Duration
+
+	// Health fails calls fast with 503 while their agent is known to be
+	// down. Nil routes every call.
+	Health *health.Tracker
+
+	// Deliveries deduplicates inbound webhook deliveries across reloads.
+	// Nil disables deduplication.
+	Deliveries *webhook.Deduper
+}
+
+// NewBuilder creates a route builder with RPC client and token verifiers.
+func NewBuilder(rpcClient *rpc.Client, verifiers *auth.VerifierSet, cfg Config) *Builder {
 	return &Builder{
-		rpc:         rpcClient,
-		jwtVerifier: jwtVerifier,
+		rpc:       rpcClient,
+		verifiers: verifiers,
+		cfg:       cfg,
 	}
 }
 
 // Build creates routes from agent manifests.
 func (b *Builder) Build(manifests []manifest.Manifest) chi.Router {
 	r := chi.NewRouter()
+	b.routes = nil
 
 	for _, m := range manifests {
 		for _, action := range m.Actions {
@@ -38,11 +99,22 @@ func (b *Builder) Build(manifests []manifest.Manifest) chi.Router {
 			if action.Auth != "" {
 				authType = action.Auth
 			}
+
+			var handler http.HandlerFunc
+			if action.Type == "webhook" {
+				verifier, err := webhookVerifier(action.Webhook)
+				if err != nil {
+					log.Printf("Warning: skipping %s.%s: %v", m.Name, action.Name, err)
+					continue
+				}
+				authType = "signature:" + verifier.Profile()
+				handler = b.buildWebhookHandler(m, action, verifier)
+			} else {
+				handler = b.buildActionHandler(m, action)
+			}
 			log.Printf("Route: %s %s -> %s.%s (auth: %s)",
 				action.HTTP.Method, pattern, m.Name, action.Name, authType)
 
-			handler := b.buildActionHandler(m, action)
-
 			switch action.HTTP.Method {
 			case "GET":
 				r.Get(pattern, handler)
@@ -54,41 +126,83 @@ func (b *Builder) Build(manifests []manifest.Manifest) chi.Router {
 				r.Delete(pattern, handler)
 			default:
 				log.Printf("Warning: unknown method %s for %s", action.HTTP.Method, pattern)
+				continue
 			}
+
+			b.routes = append(b.routes, Route{
+				Method:     action.HTTP.Method,
+				Pattern:    pattern,
+				Agent:      m.Name,
+				Action:     action.Name,
+				Auth:       authType,
+				Permission: action.Permission,
+				Scopes:     action.Scopes,
+				RateLimit:  action.RateLimit,
+				Timeout:    action.Timeout.String(),
+			})
 		}
 	}
 
 	return r
 }
 
+// Routes returns the routes mounted by the last Build.
+func (b *Builder) Routes() []Route {
+	return b.routes
+}
+
 func (b *Builder) buildActionHandler(m manifest.Manifest, action manifest.Action) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		requestID := middleware.GetRequestID(ctx)
 
 		// 1. Authentication (if required)
-		var claims *auth.Claims
-		if action.Auth == "bearer" {
-			token, err := auth.ExtractToken(r)
-			if err != nil {
-				writeError(w, http.StatusUnauthorized, "unauthorized", "Missing or invalid token", requestID)
-				return
+		claims, err := b.authenticate(r, action)
+		if err != nil {
+			if ae, ok := err.(*authError); ok && ae.err != nil {
+				log.Printf("[%s] Authentication failed: %v", requestID, ae.err)
 			}
+			writeError(w, http.StatusUnauthorized, "unauthorized", err.Error(), requestID)
+			return
+		}
+		if claims != nil {
+			ctx = auth.WithClaims(ctx, claims)
+		}
 
-			claims, err = b.jwtVerifier.Verify(token)
-			if err != nil {
-				log.Printf("[%s] JWT verification failed: %v", requestID, err)
-				writeError(w, http.StatusUnauthorized, "unauthorized", "Invalid token", requestID)
+		if len(action.Scopes) > 0 {
+			if claims == nil {
+				writeError(w, http.StatusUnauthorized, "unauthorized", "Authentication required", requestID)
+				return
+			}
+			if !hasScopes(claims.Scopes(), action.Scopes, action.ScopeMatch) {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`,
+					strings.Join(action.Scopes, " ")))
+				writeError(w, http.StatusForbidden, "forbidden", "Insufficient scope", requestID)
 				return
 			}
+		}
 
-			ctx = auth.WithClaims(ctx, claims)
+		t, verified, ok := b.resolveTenant(w, r, claims, requestID)
+		if !ok {
+			return
 		}
 
 		// 2. Parse request body
+		maxBody := b.maxBodyBytes(action)
+		if r.ContentLength > maxBody {
+			writeBodyTooLarge(w, maxBody, requestID)
+			return
+		}
+
 		var data map[string]any
 		if r.Body != nil && r.ContentLength > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBody)
 			if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					writeBodyTooLarge(w, maxBody, requestID)
+					return
+				}
 				writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON", requestID)
 				return
 			}
@@ -97,17 +211,34 @@ func (b *Builder) buildActionHandler(m manifest.Manifest, action manifest.Action
 			data = make(map[string]any)
 		}
 
+		// Fingerprinted before gateway fields are added to data
+		idem, ok := b.idempotencyRequest(w, r, action, claims, t, data, requestID)
+		if !ok {
+			return
+		}
+
 		// 3. Add auth context to event data (if authenticated)
 		if claims != nil {
-			data["_auth"] = map[string]any{
-				"user_id":  claims.UserID,
-				"username": claims.Username,
-				"roles":    claims.Roles,
+			authCtx := authData(claims, action.ForwardClaims)
+			if verified {
+				authCtx["tenant_id"] = t.ID
 			}
+			data["_auth"] = authCtx
+		}
+		if action.Auth == "optional" {
+			// Lets agents choose between personalized and anonymous responses
+			data["_personalized"] = claims != nil
 		}
 
 		// 4. Add client info
-		data["_client_ip"] = r.RemoteAddr
+		clientIP := middleware.GetClientIP(ctx)
+		if clientIP == "" {
+			clientIP = r.RemoteAddr
+		}
+		data["_client_ip"] = clientIP
+		if scheme := middleware.GetScheme(ctx); scheme != "" {
+			data["_scheme"] = scheme
+		}
 		data["_request_id"] = requestID
 
 		// 5. RPC call
@@ -116,30 +247,215 @@ func (b *Builder) buildActionHandler(m manifest.Manifest, action manifest.Action
 			timeout = 5 * time.Second
 		}
 
-		resp, err := b.rpc.Call(ctx, action.Request.Event, data, timeout)
+		opts := rpc.CallOptions{
+			Timeout:       timeout,
+			MaxEventBytes: b.maxEventBytes(action),
+			Mode:          rpc.ContentMode(m.ContentMode),
+			Subject:       routeSubject(r),
+			DataSchema:    action.Request.DataSchema,
+		}
+		if t != nil {
+			opts.Extensions = map[string]any{"tenantid": t.ID}
+			opts.RoutingKeyPrefix = t.RoutingKeyPrefix
+		}
+
+		if idem != nil {
+			// The scoped key keeps event IDs from colliding across callers
+			opts.ID = idem.scope
+			if opts.Extensions == nil {
+				opts.Extensions = make(map[string]any, 1)
+			}
+			opts.Extensions["idempotencykey"] = idem.key
+			b.serveIdempotent(w, r, idem, requestID, func(w http.ResponseWriter) {
+				b.callAgent(ctx, w, r, m.Name, action, data, opts, requestID)
+			})
+			return
+		}
+		if r.Method == http.MethodGet {
+			b.serveRead(w, r, m, action, claims, t, requestID, func(ctx context.Context, w http.ResponseWriter) {
+				b.callAgent(ctx, w, r, m.Name, action, data, opts, requestID)
+			})
+			return
+		}
+		b.callAgent(ctx, w, r, m.Name, action, data, opts, requestID)
+	}
+}
+
+// callAgent calls the agent, with retries, and maps its reply to the response.
+func (b *Builder) callAgent(ctx context.Context, w http.ResponseWriter, r *http.Request, agent string, action manifest.Action, data map[string]any, opts rpc.CallOptions, requestID string) {
+	if b.cfg.Health != nil && b.cfg.Health.Down(agent) {
+		b.setRetryAfter(w)
+		writeError(w, http.StatusServiceUnavailable, "agent_unavailable", "Agent is not running", requestID)
+		return
+	}
+
+	release := func(time.Duration, bool) {}
+	if b.cfg.Limits != nil {
+		var err error
+		release, err = b.cfg.Limits.Acquire(ctx, agent)
 		if err != nil {
-			if err == rpc.ErrTimeout {
-				writeError(w, http.StatusGatewayTimeout, "gateway_timeout", "Agent did not respond", requestID)
+			if errors.Is(err, context.Canceled) {
+				// Nobody reads this, but a recorder must not keep status 0
+				writeError(w, http.StatusServiceUnavailable, "service_unavailable", "Request canceled", requestID)
 				return
 			}
-			log.Printf("[%s] RPC error: %v", requestID, err)
-			writeError(w, http.StatusServiceUnavailable, "service_unavailable", "Agent unavailable", requestID)
+			log.Printf("[%s] Shedding call to %s: %v", requestID, agent, err)
+			b.setRetryAfter(w)
+			writeError(w, http.StatusServiceUnavailable, "overloaded", "Agent is at capacity, retry later", requestID)
+			return
+		}
+	}
+
+	start := time.Now()
+	resp, err := b.call(ctx, r, action, data, opts, requestID)
+	release(time.Since(start), errors.Is(err, rpc.ErrTimeout))
+	if err != nil {
+		if err == rpc.ErrTimeout {
+			writeTimeout(w, action.Response.Timeout, requestID)
 			return
 		}
+		if err == rpc.ErrEventTooLarge {
+			writeBodyTooLarge(w, opts.MaxEventBytes, requestID)
+			return
+		}
+		log.Printf("[%s] RPC error: %v", requestID, err)
+		writeError(w, http.StatusServiceUnavailable, "service_unavailable", "Agent unavailable", requestID)
+		return
+	}
 
-		// Map response to HTTP status
-		status := http.StatusOK
-		if resp.Type == action.Response.Failure.Event {
-			status = action.Response.Failure.Status
-			if status == 0 {
-				status = http.StatusUnauthorized
-			}
+	// Map response to HTTP status
+	status := http.StatusOK
+	if resp.Type == action.Response.Failure.Event {
+		status = action.Response.Failure.Status
+		if status == 0 {
+			status = http.StatusUnauthorized
 		}
+	}
+
+	body := resp.Data
+	if b.cfg.Session != nil {
+		body = b.applySession(w, action, status, body, requestID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// applySession sets or clears the session cookie as configured for the
+// action and returns the response body to send.
+func (b *Builder) applySession(w http.ResponseWriter, action manifest.Action, status int, body map[string]any, requestID string) map[string]any {
+	// A failed login or logout leaves the current session alone
+	if status < 200 || status > 299 {
+		return body
+	}
+	if action.Session.Clear {
+		b.cfg.Session.Clear(w)
+	}
+
+	if action.Session.SetFrom == "" {
+		return body
+	}
+
+	token, _ := body[action.Session.SetFrom].(string)
+	if token == "" {
+		log.Printf("[%s] Session: response has no %q field", requestID, action.Session.SetFrom)
+		return body
+	}
+	if err := b.cfg.Session.Set(w, token); err != nil {
+		log.Printf("[%s] Session: %v", requestID, err)
+		return body
+	}
+
+	if !action.Session.Strip {
+		return body
+	}
+	stripped := make(map[string]any, len(body))
+	for k, v := range body {
+		if k != action.Session.SetFrom {
+			stripped[k] = v
+		}
+	}
+	return stripped
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(status)
-		json.NewEncoder(w).Encode(resp.Data)
+// routeSubject derives the CloudEvent subject from route parameters,
+// e.g. /users/{id}/keys/{kid} -> "42/k1".
+func routeSubject(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
 	}
+
+	var parts []string
+	for i, key := range rctx.URLParams.Keys {
+		if key == "*" || rctx.URLParams.Values[i] == "" {
+			continue
+		}
+		parts = append(parts, rctx.URLParams.Values[i])
+	}
+	return strings.Join(parts, "/")
+}
+
+// maxBodyBytes returns the effective request body limit for an action.
+func (b *Builder) maxBodyBytes(action manifest.Action) int64 {
+	if action.MaxBodyBytes > 0 {
+		return action.MaxBodyBytes
+	}
+	if b.cfg.MaxBodyBytes > 0 {
+		return b.cfg.MaxBodyBytes
+	}
+	return 1 << 20
+}
+
+// eventOverhead is the room left above the body limit for the fields the
+// gateway adds and the CloudEvent envelope.
+const eventOverhead = 64 << 10
+
+// maxEventBytes returns the effective limit on an action's published event.
+func (b *Builder) maxEventBytes(action manifest.Action) int64 {
+	if b.cfg.MaxEventBytes > 0 {
+		return b.cfg.MaxEventBytes
+	}
+	return b.maxBodyBytes(action) + eventOverhead
+}
+
+func writeBodyTooLarge(w http.ResponseWriter, limit int64, requestID string) {
+	writeError(w, http.StatusRequestEntityTooLarge, "payload_too_large",
+		fmt.Sprintf("Request body exceeds %d bytes", limit), requestID)
+}
+
+// setRetryAfter tells shed clients when to retry, in whole seconds.
+func (b *Builder) setRetryAfter(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(max(b.cfg.RetryAfter.Seconds(), 1))))
+}
+
+// writeTimeout answers a call that got no reply in time, shaped by the
+// action's response.timeout mapping when it has one.
+func writeTimeout(w http.ResponseWriter, mapping manifest.ResponseMapping, requestID string) {
+	status := mapping.Status
+	if status == 0 {
+		status = http.StatusGatewayTimeout
+	}
+	if mapping.Body == nil {
+		writeError(w, status, "gateway_timeout", "Agent did not respond", requestID)
+		return
+	}
+
+	body := make(map[string]any, len(mapping.Body)+1)
+	for k, v := range mapping.Body {
+		body[k] = v
+	}
+	if _, ok := body["request_id"]; !ok {
+		body["request_id"] = requestID
+	}
+	writeJSON(w, status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
 }
 
 func writeError(w http.ResponseWriter, status int, errCode, message, requestID string) {
@@ -0,0 +1,173 @@
+package router
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jhaveripatric/agent-gateway/internal/auth"
+	"github.com/jhaveripatric/agent-gateway/internal/cache"
+	"github.com/jhaveripatric/agent-gateway/internal/manifest"
+	"github.com/jhaveripatric/agent-gateway/internal/tenant"
+)
+
+// sharedHeaders are the agent response headers kept in cache entries and
+// replayed to callers that did not run the fill. Per-caller headers such as
+// Set-Cookie only reach the caller whose request produced them.
+var sharedHeaders = []string{"Retry-After", "Cache-Control", "Content-Language", "Link"}
+
+// CacheTag identifies an action's entries for invalidation.
+func CacheTag(agent, action string) string {
+	return agent + "." + action
+}
+
+// serveRead answers a GET action with an ETag, honouring If-None-Match.
+// Actions with a cache TTL are served from the response cache, with
+// concurrent misses collapsed into one agent call.
+func (b *Builder) serveRead(w http.ResponseWriter, r *http.Request, m manifest.Manifest, action manifest.Action, claims *auth.Claims, t *tenant.Tenant, requestID string, exec func(context.Context, http.ResponseWriter)) {
+	if b.cfg.Cache == nil || action.Cache.TTL == 0 {
+		rec := newRecorder()
+		exec(r.Context(), rec)
+		for k, v := range rec.header {
+			w.Header()[k] = v
+		}
+		writeEntry(w, r, rec.entry(), "")
+		return
+	}
+
+	key := cacheKey(r, action, claims, t)
+	tag := CacheTag(m.Name, action.Name)
+
+	// Fills outlive the request that started them: other callers may be
+	// waiting on the result, or the caller already got a stale entry.
+	ctx := context.WithoutCancel(r.Context())
+	var own http.Header // every header, for this request if it runs the fill
+	fill := func() *cache.Entry {
+		rec := newRecorder()
+		exec(ctx, rec)
+		own = rec.header
+		e := rec.entry()
+		e.TTL = action.Cache.TTL
+		e.Stale = action.Cache.StaleWhileRevalidate
+		if e.Status >= 200 && e.Status < 300 && rec.header.Get("Set-Cookie") == "" {
+			b.cfg.Cache.Set(key, tag, e)
+		}
+		return e
+	}
+
+	if e := b.cfg.Cache.Get(key); e != nil {
+		if e.Fresh(time.Now()) {
+			writeEntry(w, r, e, "HIT")
+			return
+		}
+		if !b.cfg.Coalesce.Inflight(key) {
+			go b.cfg.Coalesce.Do(key, fill)
+		}
+		writeEntry(w, r, e, "STALE")
+		return
+	}
+
+	e, shared := b.cfg.Coalesce.Do(key, fill)
+	if e == nil {
+		writeError(w, http.StatusServiceUnavailable, "service_unavailable", "Agent unavailable", requestID)
+		return
+	}
+	if !shared {
+		for k, v := range own {
+			w.Header()[k] = v
+		}
+	}
+	writeEntry(w, r, e, "MISS")
+}
+
+// cacheKey identifies a request by tenant, route and the action's vary_by
+// dimensions.
+func cacheKey(r *http.Request, action manifest.Action, claims *auth.Claims, t *tenant.Tenant) string {
+	parts := []string{action.HTTP.Method, action.HTTP.Path}
+	if t != nil {
+		parts = append(parts, "tenant="+t.ID)
+	}
+	if slices.Contains(action.Cache.VaryBy, "user") {
+		user := ""
+		if claims != nil {
+			user = claims.UserID
+		}
+		parts = append(parts, "user="+user)
+	}
+	if slices.Contains(action.Cache.VaryBy, "params") {
+		parts = append(parts, "params="+routeSubject(r))
+	}
+	if slices.Contains(action.Cache.VaryBy, "query") {
+		parts = append(parts, "query="+r.URL.Query().Encode()) // sorted by key
+	}
+	return hash(parts...)
+}
+
+// entry converts a recorded response to a cache entry with a strong ETag
+// for successful responses.
+func (rec *recorder) entry() *cache.Entry {
+	e := &cache.Entry{
+		Status:      rec.status,
+		ContentType: rec.header.Get("Content-Type"),
+		Header:      make(http.Header),
+		Body:        rec.body.Bytes(),
+		Stored:      time.Now(),
+	}
+	for _, name := range sharedHeaders {
+		if v := rec.header.Values(name); len(v) > 0 {
+			e.Header[name] = v
+		}
+	}
+	if e.Status == 0 {
+		e.Status = http.StatusOK
+	}
+	if e.Status == http.StatusOK {
+		sum := sha256.Sum256(e.Body)
+		e.ETag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	}
+	return e
+}
+
+// writeEntry writes a response, or 304 when If-None-Match matches its ETag.
+func writeEntry(w http.ResponseWriter, r *http.Request, e *cache.Entry, cacheStatus string) {
+	for k, v := range e.Header {
+		w.Header()[k] = v
+	}
+	if cacheStatus != "" {
+		w.Header().Set("X-Cache", cacheStatus)
+		w.Header().Set("Age", strconv.Itoa(int(e.Age(time.Now()).Seconds())))
+	}
+	if e.ETag != "" {
+		w.Header().Set("ETag", e.ETag)
+		if etagMatch(r.Header.Get("If-None-Match"), e.ETag) {
+			w.Header().Del("Content-Type")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	if e.ContentType != "" {
+		w.Header().Set("Content-Type", e.ContentType)
+	}
+	w.WriteHeader(e.Status)
+	w.Write(e.Body)
+}
+
+// etagMatch applies the weak comparison If-None-Match calls for.
+func etagMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
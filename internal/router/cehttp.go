@@ -0,0 +1,96 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jhaveripatric/agent-gateway/internal/rpc"
+)
+
+// CloudEvents HTTP protocol binding media types.
+const (
+	ceStructuredType = "application/cloudevents+json"
+	ceBatchType      = "application/cloudevents-batch+json"
+)
+
+// decodeHTTPEvents reads events from a request in structured, binary or
+// batched content mode. Only JSON object data is supported.
+func decodeHTTPEvents(r *http.Request) ([]*rpc.Event, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case ceStructuredType:
+		var e rpc.Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			return nil, fmt.Errorf("decode event: %w", err)
+		}
+		return []*rpc.Event{&e}, nil
+
+	case ceBatchType:
+		var batch []*rpc.Event
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			return nil, fmt.Errorf("decode batch: %w", err)
+		}
+		if len(batch) == 0 {
+			return nil, fmt.Errorf("empty batch")
+		}
+		return batch, nil
+	}
+
+	if r.Header.Get("ce-specversion") == "" {
+		return nil, fmt.Errorf("not a CloudEvent: missing ce-specversion or CloudEvents content type")
+	}
+
+	// Binary mode: attributes in ce- headers, data in the body
+	e := &rpc.Event{DataContentType: r.Header.Get("Content-Type")}
+	for key, values := range r.Header {
+		name, ok := strings.CutPrefix(strings.ToLower(key), "ce-")
+		if !ok || len(values) == 0 {
+			continue
+		}
+		value := values[0]
+		switch name {
+		case "id":
+			e.ID = value
+		case "source":
+			e.Source = value
+		case "specversion":
+			e.SpecVersion = value
+		case "type":
+			e.Type = value
+		case "subject":
+			e.Subject = value
+		case "dataschema":
+			e.DataSchema = value
+		case "time":
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ce-time: %w", err)
+			}
+			e.Time = t
+		default:
+			e.SetExtension(name, value)
+		}
+	}
+
+	if mediaType != "" && mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return nil, fmt.Errorf("unsupported data content type %q", mediaType)
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&e.Data); err != nil {
+			return nil, fmt.Errorf("decode data: %w", err)
+		}
+	}
+	return []*rpc.Event{e}, nil
+}
+
+// writeHTTPEvent writes an event in structured content mode.
+func writeHTTPEvent(w http.ResponseWriter, status int, e *rpc.Event) {
+	w.Header().Set("Content-Type", ceStructuredType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(e)
+}
@@ -0,0 +1,153 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/jhaveripatric/agent-gateway/internal/auth"
+	"github.com/jhaveripatric/agent-gateway/internal/idempotency"
+	"github.com/jhaveripatric/agent-gateway/internal/manifest"
+	"github.com/jhaveripatric/agent-gateway/internal/middleware"
+	"github.com/jhaveripatric/agent-gateway/internal/tenant"
+)
+
+// maxIdempotencyKey bounds client-supplied keys.
+const maxIdempotencyKey = 255
+
+// idemRequest is a request carrying an idempotency key.
+type idemRequest struct {
+	key         string // client key, sent as the idempotencykey extension
+	scope       string // store key and CloudEvent id: tenant, user, route and client key
+	fingerprint string
+}
+
+// idempotencyRequest reads the idempotency key of an unsafe request. It
+// returns nil when the request has no key or the action does not use
+// keys, and false after writing an error response.
+func (b *Builder) idempotencyRequest(w http.ResponseWriter, r *http.Request, action manifest.Action, claims *auth.Claims, t *tenant.Tenant, data map[string]any, requestID string) (*idemRequest, bool) {
+	if b.cfg.Idempotency == nil || action.Idempotency == "off" || r.Method == http.MethodGet {
+		return nil, true
+	}
+
+	key := r.Header.Get(b.cfg.IdempotencyHeader)
+	if key == "" {
+		if action.Idempotency == "required" {
+			writeError(w, http.StatusBadRequest, "idempotency_key_required",
+				b.cfg.IdempotencyHeader+" header is required", requestID)
+			return nil, false
+		}
+		return nil, true
+	}
+	if len(key) > maxIdempotencyKey || !printable(key) {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Invalid "+b.cfg.IdempotencyHeader, requestID)
+		return nil, false
+	}
+
+	// Anonymous callers are told apart by client IP
+	user := "anonymous:" + middleware.GetClientIP(r.Context())
+	if claims != nil {
+		user = "user:" + claims.UserID
+	}
+	tenantID := ""
+	if t != nil {
+		tenantID = t.ID
+	}
+
+	body, _ := json.Marshal(data) // map keys are sorted, so this is canonical
+	return &idemRequest{
+		key:         key,
+		scope:       hash(tenantID, user, action.HTTP.Method, action.HTTP.Path, key),
+		fingerprint: hash(r.Method, r.URL.Path, string(body)),
+	}, true
+}
+
+// serveIdempotent runs exec once per key. Repeats get the recorded
+// response with Idempotent-Replayed set, and concurrent repeats wait for
+// the first request to finish.
+func (b *Builder) serveIdempotent(w http.ResponseWriter, r *http.Request, idem *idemRequest, requestID string, exec func(http.ResponseWriter)) {
+	rec := newRecorder()
+	resp, replayed, err := b.cfg.Idempotency.Do(r.Context(), idem.scope, idem.fingerprint, b.cfg.IdempotencyTTL,
+		func() *idempotency.Response {
+			exec(rec)
+			return &idempotency.Response{
+				Status:      rec.status,
+				ContentType: rec.header.Get("Content-Type"),
+				Body:        rec.body.Bytes(),
+			}
+		})
+	if err != nil {
+		switch {
+		case errors.Is(err, idempotency.ErrMismatch):
+			writeError(w, http.StatusUnprocessableEntity, "idempotency_key_mismatch",
+				"Idempotency key was used with a different request", requestID)
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			// Client went away while waiting for the first request
+		default:
+			log.Printf("[%s] Idempotency error: %v", requestID, err)
+			writeError(w, http.StatusServiceUnavailable, "service_unavailable", "Agent unavailable", requestID)
+		}
+		return
+	}
+
+	if !replayed {
+		// Only the first response carries headers such as session cookies
+		for k, v := range rec.header {
+			w.Header()[k] = v
+		}
+	} else {
+		w.Header().Set("Content-Type", resp.ContentType)
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+// recorder buffers a response so it can be stored and replayed.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newRecorder() *recorder {
+	return &recorder{header: make(http.Header)}
+}
+
+func (rec *recorder) Header() http.Header { return rec.header }
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *recorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(p)
+}
+
+func hash(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func printable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x21 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,176 @@
+package router
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jhaveripatric/agent-gateway/internal/manifest"
+	"github.com/jhaveripatric/agent-gateway/internal/middleware"
+	"github.com/jhaveripatric/agent-gateway/internal/rpc"
+)
+
+// IngressConfig configures the CloudEvents HTTP ingress.
+type IngressConfig struct {
+	// Auth is an action auth mode such as "bearer|api_key|mtls".
+	Auth         string
+	Timeout      time.Duration
+	MaxBodyBytes int64
+	// Allow maps callers, keyed "<auth method>:<user ID>" such as
+	// "mtls:billing-svc", to permitted event type patterns, e.g.
+	// "io.agenteco.billing.*".
+	Allow map[string][]string
+}
+
+// reservedExtensions are set by the gateway, so callers may not send them.
+var reservedExtensions = []string{"tenantid", "ingresscaller", "attempt", "idempotencykey", "deadline", "deliveryid"}
+
+// BuildIngress creates the handler that accepts CloudEvents over HTTP and
+// publishes them to the exchange. Callers sending "Prefer:
+// return=representation" wait for the agent's reply instead.
+func (b *Builder) BuildIngress(cfg IngressConfig) http.HandlerFunc {
+	action := manifest.Action{Name: "ingress", Auth: cfg.Auth}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		requestID := middleware.GetRequestID(ctx)
+
+		claims, err := b.authenticate(r, action)
+		if err != nil || claims == nil {
+			if ae, ok := err.(*authError); ok && ae.err != nil {
+				log.Printf("[%s] Ingress authentication failed: %v", requestID, ae.err)
+			}
+			writeError(w, http.StatusUnauthorized, "unauthorized", "Authentication required", requestID)
+			return
+		}
+
+		if r.ContentLength > cfg.MaxBodyBytes {
+			writeBodyTooLarge(w, cfg.MaxBodyBytes, requestID)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxBodyBytes)
+
+		// The tenant comes from the credential, as for actions
+		t, _, ok := b.resolveTenant(w, r, claims, requestID)
+		if !ok {
+			return
+		}
+
+		events, err := decodeHTTPEvents(r)
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				writeBodyTooLarge(w, cfg.MaxBodyBytes, requestID)
+				return
+			}
+			writeError(w, http.StatusBadRequest, "invalid_event", err.Error(), requestID)
+			return
+		}
+
+		for _, e := range events {
+			if err := e.Validate(); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_event", err.Error(), requestID)
+				return
+			}
+			if !ingressAllowed(cfg.Allow[claims.Method+":"+claims.UserID], e.Type) {
+				log.Printf("[%s] Ingress: %s caller %s may not send %s", requestID, claims.Method, claims.UserID, e.Type)
+				writeError(w, http.StatusForbidden, "forbidden", "Event type not allowed: "+e.Type, requestID)
+				return
+			}
+			for _, name := range reservedExtensions {
+				if _, ok := e.Extensions[name]; ok {
+					writeError(w, http.StatusBadRequest, "invalid_event", "Reserved extension: "+name, requestID)
+					return
+				}
+			}
+			// Record who injected the event for downstream auditing
+			e.SetExtension("ingresscaller", claims.UserID)
+			if t != nil {
+				e.SetExtension("tenantid", t.ID)
+			}
+		}
+
+		opts := rpc.CallOptions{Timeout: cfg.Timeout}
+		if t != nil {
+			opts.RoutingKeyPrefix = t.RoutingKeyPrefix
+		}
+
+		if wait, ok := preferReply(r, cfg.Timeout); ok {
+			if len(events) != 1 {
+				writeError(w, http.StatusBadRequest, "invalid_request", "Synchronous replies require a single event", requestID)
+				return
+			}
+			opts.Timeout = wait
+
+			resp, err := b.rpc.CallEvent(ctx, events[0], opts)
+			if err != nil {
+				if err == rpc.ErrTimeout {
+					writeError(w, http.StatusGatewayTimeout, "gateway_timeout", "Agent did not respond", requestID)
+					return
+				}
+				log.Printf("[%s] Ingress RPC error: %v", requestID, err)
+				writeError(w, http.StatusServiceUnavailable, "service_unavailable", "Agent unavailable", requestID)
+				return
+			}
+			w.Header().Set("Preference-Applied", "return=representation")
+			writeHTTPEvent(w, http.StatusOK, resp.Event)
+			return
+		}
+
+		ids := make([]string, 0, len(events))
+		for _, e := range events {
+			if err := b.rpc.Publish(ctx, e, opts); err != nil {
+				log.Printf("[%s] Ingress publish error: %v", requestID, err)
+				writeError(w, http.StatusServiceUnavailable, "service_unavailable", "Event bus unavailable", requestID)
+				return
+			}
+			ids = append(ids, e.ID)
+		}
+
+		writeJSON(w, http.StatusAccepted, map[string]any{
+			"accepted":   len(ids),
+			"ids":        ids,
+			"request_id": requestID,
+		})
+	}
+}
+
+// ingressAllowed matches an event type against a caller's patterns.
+func ingressAllowed(patterns []string, eventType string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, eventType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// preferReply reports whether the caller asked for the reply via RFC 7240
+// "Prefer: return=representation", honouring an optional "wait=N" (seconds)
+// no longer than max.
+func preferReply(r *http.Request, max time.Duration) (time.Duration, bool) {
+	wait := max
+	want := false
+
+	for _, header := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(header, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(pref), "=")
+			switch strings.ToLower(key) {
+			case "return":
+				want = strings.EqualFold(value, "representation")
+			case "wait":
+				if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+					if d := time.Duration(secs) * time.Second; d < wait {
+						wait = d
+					}
+				}
+			}
+		}
+	}
+
+	return wait, want
+}
@@ -0,0 +1,85 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"log"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jhaveripatric/agent-gateway/internal/manifest"
+	"github.com/jhaveripatric/agent-gateway/internal/rpc"
+)
+
+// call makes the action's RPC call, retrying transient failures per the
+// action's retry policy. Attempts share the event ID and the action
+// timeout, and carry their number in the "attempt" extension.
+func (b *Builder) call(ctx context.Context, r *http.Request, action manifest.Action, data map[string]any, opts rpc.CallOptions, requestID string) (*rpc.Response, error) {
+	policy := action.Retry
+	if policy.MaxAttempts <= 1 || (r.Method == http.MethodPost && opts.ID == "") {
+		return b.rpc.Call(ctx, action.Request.Event, data, opts)
+	}
+
+	if opts.ID == "" {
+		opts.ID = uuid.New().String()
+	}
+	deadline := time.Now().Add(opts.Timeout)
+	base := opts.Extensions
+	backoff := policy.Backoff
+
+	for attempt := 1; ; attempt++ {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, rpc.ErrTimeout
+		}
+
+		opts.Timeout = remaining
+		if policy.AttemptTimeout > 0 && policy.AttemptTimeout < remaining {
+			opts.Timeout = policy.AttemptTimeout
+		}
+		opts.Extensions = maps.Clone(base)
+		if opts.Extensions == nil {
+			opts.Extensions = make(map[string]any, 1)
+		}
+		opts.Extensions["attempt"] = attempt
+
+		resp, err := b.rpc.Call(ctx, action.Request.Event, data, opts)
+		reason := retryReason(policy, resp, err)
+		if reason == "" || attempt >= policy.MaxAttempts {
+			return resp, err
+		}
+
+		// Equal jitter: half the current backoff plus a random share of the
+		// other half, within the deadline
+		wait := backoff/2 + rand.N(backoff/2+1)
+		if wait >= time.Until(deadline) {
+			return resp, err
+		}
+		log.Printf("[%s] Retrying %s after %s (attempt %d/%d)",
+			requestID, action.Request.Event, reason, attempt+1, policy.MaxAttempts)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff = min(backoff*2, policy.MaxBackoff)
+	}
+}
+
+// retryReason returns the retryable condition an attempt hit, or "".
+func retryReason(policy manifest.RetryConfig, resp *rpc.Response, err error) string {
+	switch {
+	case errors.Is(err, rpc.ErrPublish) && slices.Contains(policy.On, "publish_error"):
+		return "publish error"
+	case errors.Is(err, rpc.ErrTimeout) && slices.Contains(policy.On, "timeout"):
+		return "timeout"
+	case err == nil && slices.Contains(policy.Events, resp.Type):
+		return resp.Type
+	}
+	return ""
+}
@@ -0,0 +1,14 @@
+package router
+
+// Route describes a mounted agent route.
+type Route struct {
+	Method     string   `json:"method"`
+	Pattern    string   `json:"pattern"`
+	Agent      string   `json:"agent"`
+	Action     string   `json:"action"`
+	Auth       string   `json:"auth"`
+	Permission string   `json:"permission,omitempty"`
+	Scopes     []string `json:"scopes,omitempty"`
+	RateLimit  string   `json:"rate_limit,omitempty"`
+	Timeout    string   `json:"timeout"`
+}
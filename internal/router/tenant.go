@@ -0,0 +1,70 @@
+package router
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/jhaveripatric/agent-gateway/internal/auth"
+	"github.com/jhaveripatric/agent-gateway/internal/tenant"
+)
+
+// resolveTenant settles the request tenant once claims are known. A tenant
+// from the token, or bound to the credential, must agree with one resolved
+// from the request, and is rate limited here when it is the only source.
+// The header, subdomain and path are client input, so an authenticated
+// caller may only use them to repeat a verified tenant. verified reports
+// whether the tenant came from the credential. It writes the error
+// response and returns false when the request must stop.
+func (b *Builder) resolveTenant(w http.ResponseWriter, r *http.Request, claims *auth.Claims, requestID string) (t *tenant.Tenant, verified, ok bool) {
+	reg := b.cfg.Tenants
+	if reg == nil {
+		return nil, false, true
+	}
+
+	t = tenant.GetTenant(r.Context())
+
+	var claimID string
+	if claims != nil {
+		if name := reg.ClaimName(); name != "" {
+			if v, ok := claims.Claim(name); ok {
+				claimID, _ = v.(string)
+			}
+		}
+		if claimID == "" {
+			claimID = claims.Tenant
+		}
+		if claimID == "" && t != nil {
+			log.Printf("[%s] Unverified tenant %s for %s caller %s", requestID, t.ID, claims.Method, claims.UserID)
+			writeError(w, http.StatusForbidden, "tenant_unverified", "Credential is not bound to a tenant", requestID)
+			return nil, false, false
+		}
+	}
+
+	if claimID != "" {
+		if t != nil && t.ID != claimID {
+			log.Printf("[%s] Tenant mismatch: request %s, token %s", requestID, t.ID, claimID)
+			writeError(w, http.StatusForbidden, "tenant_mismatch", "Token does not belong to this tenant", requestID)
+			return nil, false, false
+		}
+		if t == nil {
+			ct, ok := reg.Get(claimID)
+			if !ok {
+				writeError(w, http.StatusForbidden, "unknown_tenant", "Unknown tenant", requestID)
+				return nil, false, false
+			}
+			if ok, retryAfter := ct.Allow(); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				writeError(w, http.StatusTooManyRequests, "rate_limited", "Tenant rate limit exceeded", requestID)
+				return nil, false, false
+			}
+			t = ct
+		}
+	}
+
+	if t == nil && reg.Required() {
+		writeError(w, http.StatusBadRequest, "tenant_required", "Tenant could not be determined", requestID)
+		return nil, false, false
+	}
+	return t, claimID != "", true
+}
@@ -0,0 +1,133 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/jhaveripatric/agent-gateway/internal/manifest"
+	"github.com/jhaveripatric/agent-gateway/internal/middleware"
+	"github.com/jhaveripatric/agent-gateway/internal/rpc"
+	"github.com/jhaveripatric/agent-gateway/internal/webhook"
+)
+
+// buildWebhookHandler verifies a third-party webhook and publishes its raw
+// payload without waiting for a reply. Deliveries already seen within the
+// dedupe TTL are acknowledged without publishing again.
+func (b *Builder) buildWebhookHandler(m manifest.Manifest, action manifest.Action, verifier *webhook.Verifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		requestID := middleware.GetRequestID(ctx)
+
+		t, _, ok := b.resolveTenant(w, r, nil, requestID)
+		if !ok {
+			return
+		}
+
+		// Signatures cover the exact bytes, so read the body raw
+		maxBody := b.maxBodyBytes(action)
+		if r.ContentLength > maxBody {
+			writeBodyTooLarge(w, maxBody, requestID)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				writeBodyTooLarge(w, maxBody, requestID)
+				return
+			}
+			writeError(w, http.StatusBadRequest, "invalid_request", "Could not read body", requestID)
+			return
+		}
+
+		deliveryID, err := verifier.Verify(r.Header, body)
+		if err != nil {
+			log.Printf("[%s] Webhook %s.%s rejected: %v", requestID, m.Name, action.Name, err)
+			writeError(w, http.StatusUnauthorized, "unauthorized", "Invalid signature", requestID)
+			return
+		}
+
+		key := m.Name + "/" + action.Name + "/" + deliveryID
+		if b.cfg.Deliveries != nil && !b.cfg.Deliveries.Claim(key, action.Webhook.DedupeTTL) {
+			writeJSON(w, http.StatusOK, map[string]string{
+				"status":      "duplicate",
+				"delivery_id": deliveryID,
+			})
+			return
+		}
+
+		// Keep JSON payloads byte-for-byte; anything else travels as a string
+		var payload any = string(body)
+		if json.Valid(body) {
+			payload = json.RawMessage(body)
+		}
+		data := map[string]any{
+			"payload":      payload,
+			"content_type": r.Header.Get("Content-Type"),
+			"_request_id":  requestID,
+		}
+		if len(action.Webhook.ForwardHeaders) > 0 {
+			headers := make(map[string]string, len(action.Webhook.ForwardHeaders))
+			for _, name := range action.Webhook.ForwardHeaders {
+				if v := r.Header.Get(name); v != "" {
+					headers[name] = v
+				}
+			}
+			data["headers"] = headers
+		}
+		if clientIP := middleware.GetClientIP(ctx); clientIP != "" {
+			data["_client_ip"] = clientIP
+		}
+
+		event := rpc.NewEvent(action.Request.Event, b.rpc.Source(), data)
+		event.Subject = routeSubject(r)
+		event.DataSchema = action.Request.DataSchema
+		event.SetExtension("deliveryid", deliveryID)
+
+		opts := rpc.CallOptions{
+			MaxEventBytes: b.maxEventBytes(action),
+			Mode:          rpc.ContentMode(m.ContentMode),
+		}
+		if t != nil {
+			event.SetExtension("tenantid", t.ID)
+			opts.RoutingKeyPrefix = t.RoutingKeyPrefix
+		}
+
+		if err := b.rpc.Publish(ctx, event, opts); err != nil {
+			// Let the provider's retry go through
+			if b.cfg.Deliveries != nil {
+				b.cfg.Deliveries.Forget(key)
+			}
+			if err == rpc.ErrEventTooLarge {
+				writeBodyTooLarge(w, opts.MaxEventBytes, requestID)
+				return
+			}
+			log.Printf("[%s] Webhook publish error: %v", requestID, err)
+			writeError(w, http.StatusServiceUnavailable, "service_unavailable", "Could not accept webhook", requestID)
+			return
+		}
+
+		writeJSON(w, http.StatusAccepted, map[string]string{
+			"status":      "accepted",
+			"id":          event.ID,
+			"delivery_id": deliveryID,
+		})
+	}
+}
+
+// webhookVerifier builds the signature verifier for a webhook action.
+func webhookVerifier(cfg manifest.WebhookConfig) (*webhook.Verifier, error) {
+	return webhook.NewVerifier(webhook.VerifyConfig{
+		Profile:         cfg.Profile,
+		Secret:          cfg.Secret,
+		Header:          cfg.Header,
+		Prefix:          cfg.Prefix,
+		Encoding:        cfg.Encoding,
+		TimestampHeader: cfg.TimestampHeader,
+		Tolerance:       cfg.Tolerance,
+		IDField:         cfg.IDField,
+	})
+}
@@ -1,8 +1,11 @@
 package rpc
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
@@ -12,9 +15,12 @@ type Client struct {
 	conn       *amqp.Connection
 	channel    *amqp.Channel
 	exchange   string
+	source     string
 	replyQueue string
+	bindings   []string
 
-	pending map[string]chan *Response
+	pending map[string]*pendingCall
+	subs    []subscription
 	mu      sync.RWMutex
 
 	closed bool
@@ -24,12 +30,28 @@ type Client struct {
 type Config struct {
 	URL      string
 	Exchange string
+	Source   string // CloudEvent source of published events
+}
+
+// pendingCall tracks an in-flight request awaiting its reply.
+type pendingCall struct {
+	ch        chan *Response
+	eventType string
+	started   time.Time
+}
+
+// PendingCall is a snapshot of an in-flight request.
+type PendingCall struct {
+	CorrelationID string
+	EventType     string
+	Age           time.Duration
 }
 
 // Response holds the response from an agent.
 type Response struct {
-	Type string
-	Data map[string]any
+	Type  string
+	Data  map[string]any
+	Event *Event
 }
 
 // NewClient creates a new RPC client connected to RabbitMQ.
@@ -71,12 +93,19 @@ func NewClient(cfg Config) (*Client, error) {
 		}
 	}
 
+	source := cfg.Source
+	if source == "" {
+		source = "/agent-gateway"
+	}
+
 	client := &Client{
 		conn:       conn,
 		channel:    ch,
 		exchange:   cfg.Exchange,
+		source:     source,
 		replyQueue: q.Name,
-		pending:    make(map[string]chan *Response),
+		bindings:   patterns,
+		pending:    make(map[string]*pendingCall),
 	}
 
 	// Start consumer
@@ -104,3 +133,63 @@ func (c *Client) Close() error {
 func (c *Client) Ready() bool {
 	return c.conn != nil && !c.conn.IsClosed()
 }
+
+// ErrQueueNotFound is returned by QueueConsumers for a missing queue.
+var ErrQueueNotFound = errors.New("queue not found")
+
+// QueueConsumers returns the number of consumers on a queue using a passive
+// declare. A failed passive declare closes its channel, so each probe uses
+// a channel of its own.
+func (c *Client) QueueConsumers(name string) (int, error) {
+	ch, err := c.conn.Channel()
+	if err != nil {
+		return 0, fmt.Errorf("open channel: %w", err)
+	}
+	defer ch.Close()
+
+	q, err := ch.QueueDeclarePassive(name, false, false, false, false, nil)
+	if err != nil {
+		var amqpErr *amqp.Error
+		if errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound {
+			return 0, ErrQueueNotFound
+		}
+		return 0, fmt.Errorf("inspect %s: %w", name, err)
+	}
+	return q.Consumers, nil
+}
+
+// Bindings returns the routing key patterns bound to the reply queue.
+func (c *Client) Bindings() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.bindings...)
+}
+
+// ReplyQueue returns the name of the exclusive reply queue.
+func (c *Client) ReplyQueue() string {
+	return c.replyQueue
+}
+
+// Source returns the CloudEvent source of events this client publishes.
+func (c *Client) Source() string {
+	return c.source
+}
+
+// Pending returns a snapshot of in-flight calls, oldest first.
+func (c *Client) Pending() []PendingCall {
+	now := time.Now()
+
+	c.mu.RLock()
+	calls := make([]PendingCall, 0, len(c.pending))
+	for id, p := range c.pending {
+		calls = append(calls, PendingCall{
+			CorrelationID: id,
+			EventType:     p.eventType,
+			Age:           now.Sub(p.started),
+		})
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(calls, func(i, j int) bool { return calls[i].Age > calls[j].Age })
+	return calls
+}
@@ -1,7 +1,6 @@
 package rpc
 
 import (
-	"encoding/json"
 	"log"
 
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -20,20 +19,19 @@ func (c *Client) consume() {
 }
 
 func (c *Client) handleMessage(msg amqp.Delivery) {
-	// Parse CloudEvent
-	var event struct {
-		Type string         `json:"type"`
-		Data map[string]any `json:"data"`
-	}
-
-	if err := json.Unmarshal(msg.Body, &event); err != nil {
+	// Parse CloudEvent (structured or binary mode)
+	event, err := eventFromDelivery(msg)
+	if err != nil {
 		log.Printf("failed to parse response: %v", err)
 		return
 	}
 
+	resp := &Response{Type: event.Type, Data: event.Data, Event: event}
+	c.dispatch(msg.RoutingKey, resp)
+
 	// Find pending request by correlation ID
 	c.mu.RLock()
-	respChan, ok := c.pending[msg.CorrelationId]
+	call, ok := c.pending[msg.CorrelationId]
 	c.mu.RUnlock()
 
 	if !ok {
@@ -43,7 +41,7 @@ func (c *Client) handleMessage(msg amqp.Delivery) {
 
 	// Send response
 	select {
-	case respChan <- &Response{Type: event.Type, Data: event.Data}:
+	case call.ch <- resp:
 	default:
 		// Channel full or closed
 	}
@@ -0,0 +1,293 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ContentMode selects how an event is carried in an AMQP message.
+type ContentMode string
+
+const (
+	// ModeStructured sends the whole event as a JSON envelope.
+	ModeStructured ContentMode = "structured"
+	// ModeBinary sends attributes as AMQP headers and data as the body.
+	ModeBinary ContentMode = "binary"
+)
+
+// headerPrefix marks CloudEvent attributes in AMQP headers, per the
+// CloudEvents AMQP protocol binding.
+const headerPrefix = "cloudEvents:"
+
+const structuredContentType = "application/cloudevents+json"
+
+// Event is a CloudEvents 1.0 event.
+type Event struct {
+	ID              string
+	Source          string
+	SpecVersion     string
+	Type            string
+	DataContentType string
+	DataSchema      string
+	Subject         string
+	Time            time.Time
+	Extensions      map[string]any
+	Data            map[string]any
+}
+
+// contextAttributes are the attribute names defined by the spec, which
+// extensions may not reuse.
+var contextAttributes = map[string]bool{
+	"id": true, "source": true, "specversion": true, "type": true,
+	"datacontenttype": true, "dataschema": true, "subject": true,
+	"time": true, "data": true, "data_base64": true,
+}
+
+// NewEvent creates an event with a fresh ID and the current time.
+func NewEvent(eventType, source string, data map[string]any) *Event {
+	return &Event{
+		ID:              uuid.New().String(),
+		Source:          source,
+		SpecVersion:     "1.0",
+		Type:            eventType,
+		DataContentType: "application/json",
+		Time:            time.Now().UTC(),
+		Data:            data,
+	}
+}
+
+// SetExtension sets an extension attribute.
+func (e *Event) SetExtension(name string, value any) {
+	if e.Extensions == nil {
+		e.Extensions = make(map[string]any)
+	}
+	e.Extensions[name] = value
+}
+
+// Validate checks required attributes and extension naming rules.
+func (e *Event) Validate() error {
+	if e.ID == "" {
+		return fmt.Errorf("event id is required")
+	}
+	if e.Source == "" {
+		return fmt.Errorf("event source is required")
+	}
+	if e.SpecVersion != "1.0" {
+		return fmt.Errorf("unsupported specversion %q", e.SpecVersion)
+	}
+	if e.Type == "" {
+		return fmt.Errorf("event type is required")
+	}
+	for name, value := range e.Extensions {
+		if !validExtensionName(name) {
+			return fmt.Errorf("invalid extension name %q", name)
+		}
+		switch value.(type) {
+		case string, bool, int, int32, int64, float64, time.Time:
+		default:
+			return fmt.Errorf("extension %s: unsupported value type %T", name, value)
+		}
+	}
+	return nil
+}
+
+// validExtensionName allows lowercase letters and digits, up to 20
+// characters, and rejects names of context attributes.
+func validExtensionName(name string) bool {
+	if name == "" || len(name) > 20 || contextAttributes[name] {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalJSON encodes the event in structured JSON format.
+func (e *Event) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any, len(e.Extensions)+9)
+	for name, value := range e.Extensions {
+		m[name] = value
+	}
+	m["specversion"] = e.SpecVersion
+	m["id"] = e.ID
+	m["source"] = e.Source
+	m["type"] = e.Type
+	if e.DataContentType != "" {
+		m["datacontenttype"] = e.DataContentType
+	}
+	if e.DataSchema != "" {
+		m["dataschema"] = e.DataSchema
+	}
+	if e.Subject != "" {
+		m["subject"] = e.Subject
+	}
+	if !e.Time.IsZero() {
+		m["time"] = e.Time.Format(time.RFC3339Nano)
+	}
+	if e.Data != nil {
+		m["data"] = e.Data
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes a structured JSON event. Unknown top-level
+// attributes become extensions.
+func (e *Event) UnmarshalJSON(b []byte) error {
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	*e = Event{}
+	for name, value := range m {
+		switch name {
+		case "id":
+			e.ID, _ = value.(string)
+		case "source":
+			e.Source, _ = value.(string)
+		case "specversion":
+			e.SpecVersion, _ = value.(string)
+		case "type":
+			e.Type, _ = value.(string)
+		case "datacontenttype":
+			e.DataContentType, _ = value.(string)
+		case "dataschema":
+			e.DataSchema, _ = value.(string)
+		case "subject":
+			e.Subject, _ = value.(string)
+		case "time":
+			if s, ok := value.(string); ok {
+				e.Time, _ = time.Parse(time.RFC3339Nano, s)
+			}
+		case "data":
+			e.Data, _ = value.(map[string]any)
+		default:
+			e.SetExtension(name, value)
+		}
+	}
+	return nil
+}
+
+// publishing encodes the event as an AMQP message in the given mode.
+func (e *Event) publishing(mode ContentMode) (amqp.Publishing, error) {
+	if mode != ModeBinary {
+		body, err := json.Marshal(e)
+		if err != nil {
+			return amqp.Publishing{}, fmt.Errorf("marshal event: %w", err)
+		}
+		return amqp.Publishing{
+			ContentType: structuredContentType,
+			MessageId:   e.ID,
+			Body:        body,
+		}, nil
+	}
+
+	body, err := json.Marshal(e.Data)
+	if err != nil {
+		return amqp.Publishing{}, fmt.Errorf("marshal event data: %w", err)
+	}
+
+	headers := amqp.Table{
+		headerPrefix + "id":          e.ID,
+		headerPrefix + "source":      e.Source,
+		headerPrefix + "specversion": e.SpecVersion,
+		headerPrefix + "type":        e.Type,
+	}
+	if e.DataSchema != "" {
+		headers[headerPrefix+"dataschema"] = e.DataSchema
+	}
+	if e.Subject != "" {
+		headers[headerPrefix+"subject"] = e.Subject
+	}
+	if !e.Time.IsZero() {
+		headers[headerPrefix+"time"] = e.Time
+	}
+	for name, value := range e.Extensions {
+		if i, ok := value.(int); ok {
+			value = int64(i)
+		}
+		headers[headerPrefix+name] = value
+	}
+
+	return amqp.Publishing{
+		ContentType: e.DataContentType,
+		MessageId:   e.ID,
+		Headers:     headers,
+		Body:        body,
+	}, nil
+}
+
+// eventFromDelivery decodes an AMQP message in either content mode.
+func eventFromDelivery(msg amqp.Delivery) (*Event, error) {
+	if !isBinary(msg.Headers) {
+		var e Event
+		if err := json.Unmarshal(msg.Body, &e); err != nil {
+			return nil, err
+		}
+		return &e, nil
+	}
+
+	e := &Event{DataContentType: msg.ContentType}
+	for key, value := range msg.Headers {
+		name, ok := attributeName(key)
+		if !ok {
+			continue
+		}
+		switch name {
+		case "id":
+			e.ID, _ = value.(string)
+		case "source":
+			e.Source, _ = value.(string)
+		case "specversion":
+			e.SpecVersion, _ = value.(string)
+		case "type":
+			e.Type, _ = value.(string)
+		case "dataschema":
+			e.DataSchema, _ = value.(string)
+		case "subject":
+			e.Subject, _ = value.(string)
+		case "time":
+			switch t := value.(type) {
+			case time.Time:
+				e.Time = t
+			case string:
+				e.Time, _ = time.Parse(time.RFC3339Nano, t)
+			}
+		default:
+			e.SetExtension(name, value)
+		}
+	}
+
+	if len(msg.Body) > 0 {
+		if err := json.Unmarshal(msg.Body, &e.Data); err != nil {
+			return nil, fmt.Errorf("decode data: %w", err)
+		}
+	}
+	return e, nil
+}
+
+func isBinary(headers amqp.Table) bool {
+	for key := range headers {
+		if name, ok := attributeName(key); ok && name == "specversion" {
+			return true
+		}
+	}
+	return false
+}
+
+// attributeName strips the binding prefix; "cloudEvents_" is accepted as
+// used by JMS-style clients.
+func attributeName(header string) (string, bool) {
+	if name, ok := strings.CutPrefix(header, headerPrefix); ok {
+		return name, true
+	}
+	return strings.CutPrefix(header, "cloudEvents_")
+}
@@ -2,8 +2,8 @@ package rpc
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,14 +14,82 @@ import (
 // ErrTimeout is returned when an RPC call times out.
 var ErrTimeout = fmt.Errorf("request timeout")
 
+// ErrEventTooLarge is returned when the encoded event exceeds MaxEventBytes.
+var ErrEventTooLarge = fmt.Errorf("event too large")
+
+// ErrPublish wraps errors from publishing to the exchange. Nothing reached
+// the agent, so the call is safe to retry.
+var ErrPublish = fmt.Errorf("publish")
+
+// CallOptions tunes a single Call.
+type CallOptions struct {
+	Timeout       time.Duration
+	MaxEventBytes int64 // 0 means unlimited
+
+	// ID replaces the generated event ID, e.g. with an idempotency key.
+	ID string
+
+	// Mode selects structured (default) or binary content mode.
+	Mode ContentMode
+	// Subject and DataSchema set the matching CloudEvent attributes.
+	Subject    string
+	DataSchema string
+	// Extensions are added as CloudEvent extension attributes.
+	Extensions map[string]any
+	// RoutingKeyPrefix is prepended to the routing key, e.g. to shard by tenant.
+	RoutingKeyPrefix string
+}
+
 // Call publishes an event and waits for response.
-func (c *Client) Call(ctx context.Context, eventType string, data map[string]any, timeout time.Duration) (*Response, error) {
+func (c *Client) Call(ctx context.Context, eventType string, data map[string]any, opts CallOptions) (*Response, error) {
+	event := NewEvent(eventType, c.source, data)
+	if opts.ID != "" {
+		event.ID = opts.ID
+	}
+	event.Subject = opts.Subject
+	event.DataSchema = opts.DataSchema
+	for name, value := range opts.Extensions {
+		event.SetExtension(name, value)
+	}
+
+	return c.CallEvent(ctx, event, opts)
+}
+
+// CallEvent publishes a prepared event and waits for its reply.
+func (c *Client) CallEvent(ctx context.Context, event *Event, opts CallOptions) (*Response, error) {
+	// Nobody waits for a reply past the deadline, so tell the agent and
+	// let the broker drop the request if it is still queued by then.
+	deadline := time.Now().Add(opts.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	event.SetExtension("deadline", deadline.UTC())
+
+	if err := event.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+
+	msg, err := event.publishing(opts.Mode)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxEventBytes > 0 && int64(len(msg.Body)) > opts.MaxEventBytes {
+		return nil, ErrEventTooLarge
+	}
+	msg.Expiration = expiration(time.Until(deadline))
+
 	correlationID := uuid.New().String()
+	msg.CorrelationId = correlationID
+	msg.ReplyTo = c.replyQueue
 
 	// Create response channel
 	respChan := make(chan *Response, 1)
 	c.mu.Lock()
-	c.pending[correlationID] = respChan
+	c.pending[correlationID] = &pendingCall{
+		ch:        respChan,
+		eventType: event.Type,
+		started:   time.Now(),
+	}
 	c.mu.Unlock()
 
 	defer func() {
@@ -30,50 +98,66 @@ func (c *Client) Call(ctx context.Context, eventType string, data map[string]any
 		c.mu.Unlock()
 	}()
 
-	// Build CloudEvent
-	event := map[string]any{
-		"specversion":     "1.0",
-		"id":              uuid.New().String(),
-		"type":            eventType,
-		"source":          "/agent-gateway",
-		"time":            time.Now().Format(time.RFC3339),
-		"datacontenttype": "application/json",
-		"data":            data,
+	// Publish
+	if err := c.publish(ctx, event.Type, opts.RoutingKeyPrefix, msg); err != nil {
+		return nil, err
+	}
+
+	// Wait for response
+	select {
+	case resp := <-respChan:
+		return resp, nil
+	case <-time.After(time.Until(deadline)):
+		return nil, ErrTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Publish sends an event without waiting for a reply.
+func (c *Client) Publish(ctx context.Context, event *Event, opts CallOptions) error {
+	if err := event.Validate(); err != nil {
+		return fmt.Errorf("invalid event: %w", err)
 	}
 
-	body, err := json.Marshal(event)
+	msg, err := event.publishing(opts.Mode)
 	if err != nil {
-		return nil, fmt.Errorf("marshal event: %w", err)
+		return err
+	}
+	if opts.MaxEventBytes > 0 && int64(len(msg.Body)) > opts.MaxEventBytes {
+		return ErrEventTooLarge
 	}
 
+	return c.publish(ctx, event.Type, opts.RoutingKeyPrefix, msg)
+}
+
+func (c *Client) publish(ctx context.Context, eventType, prefix string, msg amqp.Publishing) error {
 	// Extract routing key from event type
 	routingKey := extractRoutingKey(eventType)
+	if prefix != "" {
+		routingKey = prefix + "." + routingKey
+	}
 
-	// Publish
-	err = c.channel.PublishWithContext(ctx,
+	err := c.channel.PublishWithContext(ctx,
 		c.exchange,
 		routingKey,
 		false, false,
-		amqp.Publishing{
-			ContentType:   "application/json",
-			CorrelationId: correlationID,
-			ReplyTo:       c.replyQueue,
-			Body:          body,
-		},
+		msg,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("publish: %w", err)
+		return fmt.Errorf("%w: %w", ErrPublish, err)
 	}
+	return nil
+}
 
-	// Wait for response
-	select {
-	case resp := <-respChan:
-		return resp, nil
-	case <-time.After(timeout):
-		return nil, ErrTimeout
-	case <-ctx.Done():
-		return nil, ctx.Err()
+// expiration formats a per-message TTL in whole milliseconds, rounded up so
+// a short remaining time never becomes "0".
+func expiration(d time.Duration) string {
+	ms := (d + time.Millisecond - 1) / time.Millisecond
+	if ms < 1 {
+		ms = 1
 	}
+	return strconv.FormatInt(int64(ms), 10)
 }
 
 // extractRoutingKey converts event type to routing key.
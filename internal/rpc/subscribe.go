@@ -0,0 +1,156 @@
+package rpc
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Handler processes an event delivered to a subscription. Handlers run on
+// the consumer goroutine and must not block.
+type Handler func(routingKey string, event *Response)
+
+type subscription struct {
+	pattern string
+	handler Handler
+}
+
+// Subscribe binds the reply queue to a routing key pattern and invokes
+// handler for every matching event, whether or not it is also the reply
+// to a pending Call. The queue is exclusive to this gateway instance, so
+// each instance receives its own copy of broadcast events.
+func (c *Client) Subscribe(pattern string, handler Handler) error {
+	if err := c.Bind(pattern); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.subs = append(c.subs, subscription{pattern: pattern, handler: handler})
+	c.mu.Unlock()
+	return nil
+}
+
+// Bind adds a routing key pattern to the reply queue so replies published
+// under that pattern reach pending calls.
+func (c *Client) Bind(pattern string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if contains(c.bindings, pattern) {
+		return nil
+	}
+	if err := c.channel.QueueBind(c.replyQueue, pattern, c.exchange, false, nil); err != nil {
+		return fmt.Errorf("bind %s: %w", pattern, err)
+	}
+	c.bindings = append(c.bindings, pattern)
+	return nil
+}
+
+// dispatch invokes subscription handlers whose pattern matches routingKey.
+func (c *Client) dispatch(routingKey string, event *Response) {
+	c.mu.RLock()
+	var handlers []Handler
+	for _, s := range c.subs {
+		if TopicMatch(s.pattern, routingKey) {
+			handlers = append(handlers, s.handler)
+		}
+	}
+	c.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(routingKey, event)
+	}
+}
+
+// ConsumeQueue declares a durable queue shared by all gateway instances,
+// binds it to the patterns and passes each event to handler on its own
+// channel. Messages are acknowledged when handler returns nil and requeued
+// otherwise, so each event is handled by one instance at least once.
+func (c *Client) ConsumeQueue(queue string, patterns []string, handler func(routingKey string, event *Event) error) error {
+	ch, err := c.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("open channel: %w", err)
+	}
+
+	if _, err := ch.QueueDeclare(queue, true, false, false, false, nil); err != nil {
+		ch.Close()
+		return fmt.Errorf("declare %s: %w", queue, err)
+	}
+	for _, pattern := range patterns {
+		if err := ch.QueueBind(queue, pattern, c.exchange, false, nil); err != nil {
+			ch.Close()
+			return fmt.Errorf("bind %s to %s: %w", queue, pattern, err)
+		}
+	}
+	if err := ch.Qos(32, 0, false); err != nil {
+		ch.Close()
+		return fmt.Errorf("set qos: %w", err)
+	}
+
+	msgs, err := ch.Consume(queue, "", false, false, false, false, nil)
+	if err != nil {
+		ch.Close()
+		return fmt.Errorf("consume %s: %w", queue, err)
+	}
+
+	go func() {
+		for msg := range msgs {
+			event, err := eventFromDelivery(msg)
+			if err != nil {
+				log.Printf("%s: dropping undecodable message: %v", queue, err)
+				msg.Ack(false)
+				continue
+			}
+			if err := handler(msg.RoutingKey, event); err != nil {
+				log.Printf("%s: handler failed, requeueing: %v", queue, err)
+				msg.Nack(false, true)
+				continue
+			}
+			msg.Ack(false)
+		}
+	}()
+
+	return nil
+}
+
+// TopicMatch reports whether a routing key matches an AMQP topic pattern,
+// where "*" matches one word and "#" matches zero or more words.
+func TopicMatch(pattern, key string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(key, "."))
+}
+
+func matchWords(pattern, key []string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case "#":
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if matchWords(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case "*":
+			if len(key) == 0 {
+				return false
+			}
+		default:
+			if len(key) == 0 || key[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, key = pattern[1:], key[1:]
+	}
+	return len(key) == 0
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
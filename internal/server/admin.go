@@ -0,0 +1,229 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jhaveripatric/agent-gateway/internal/middleware"
+	"github.com/jhaveripatric/agent-gateway/internal/webhook"
+)
+
+// adminRouter builds the handler for the separately bound admin listener.
+func (s *Server) adminRouter() chi.Router {
+	r := chi.NewRouter()
+
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Recovery)
+	r.Use(middleware.Logger)
+
+	r.Route("/admin", func(r chi.Router) {
+		// Read-only introspection
+		r.Get("/routes", s.adminRoutes)
+		r.Get("/manifests", s.adminManifests)
+		r.Get("/keys", s.adminKeys)
+		r.Get("/api-keys", s.adminAPIKeys)
+		r.Get("/revocations", s.adminRevocations)
+		r.Get("/bindings", s.adminBindings)
+		r.Get("/pending", s.adminPending)
+		r.Get("/limits", s.adminLimits)
+		r.Get("/webhooks", s.adminWebhooks)
+		r.Get("/webhooks/dead-letters", s.adminDeadLetters)
+
+		// Operations
+		r.Post("/reload", s.adminReload)
+		r.Post("/drain", s.adminDrain)
+		r.Post("/webhooks/dead-letters/{id}/redeliver", s.adminRedeliver)
+	})
+
+	return r
+}
+
+func (s *Server) adminRoutes(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	routes := s.routes
+	s.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{"routes": routes})
+}
+
+func (s *Server) adminManifests(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	manifests := make([]map[string]any, 0, len(s.manifests))
+	for _, m := range s.manifests {
+		manifests = append(manifests, map[string]any{
+			"name":    m.Name,
+			"version": m.Version,
+			"path":    m.ManifestPath,
+			"actions": len(m.Actions),
+		})
+	}
+	s.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{"manifests": manifests})
+}
+
+func (s *Server) adminKeys(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"keys": s.verifiers.Keys()})
+}
+
+func (s *Server) adminAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if s.apiKeys == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"api_keys": []any{}})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"api_keys": s.apiKeys.Usage()})
+}
+
+func (s *Server) adminRevocations(w http.ResponseWriter, r *http.Request) {
+	if s.revocations == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"enabled": false})
+		return
+	}
+	tokens, users := s.revocations.Len()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"enabled": true,
+		"tokens":  tokens,
+		"users":   users,
+	})
+}
+
+func (s *Server) adminBindings(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"exchange":    s.cfg.Infrastructure.RabbitMQ.Exchange,
+		"reply_queue": s.rpcClient.ReplyQueue(),
+		"bindings":    s.rpcClient.Bindings(),
+	})
+}
+
+func (s *Server) adminPending(w http.ResponseWriter, r *http.Request) {
+	calls := s.rpcClient.Pending()
+
+	pending := make([]map[string]any, 0, len(calls))
+	for _, c := range calls {
+		pending = append(pending, map[string]any{
+			"correlation_id": c.CorrelationID,
+			"event_type":     c.EventType,
+			"age":            c.Age.String(),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"count":   len(pending),
+		"pending": pending,
+	})
+}
+
+func (s *Server) adminLimits(w http.ResponseWriter, r *http.Request) {
+	if s.limits == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"enabled": false})
+		return
+	}
+
+	global, agents := s.limits.Stats()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"enabled": true,
+		"global":  global,
+		"agents":  agents,
+	})
+}
+
+func (s *Server) adminReload(w http.ResponseWriter, r *http.Request) {
+	if err := s.Reload(); err != nil {
+		log.Printf("[%s] Reload failed: %v", middleware.GetRequestID(r.Context()), err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"status": "error",
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	s.mu.RLock()
+	manifests, routes := len(s.manifests), len(s.routes)
+	s.mu.RUnlock()
+
+	log.Printf("Reloaded %d manifests (%d routes)", manifests, routes)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":    "reloaded",
+		"manifests": manifests,
+		"routes":    routes,
+	})
+}
+
+func (s *Server) adminDrain(w http.ResponseWriter, r *http.Request) {
+	s.Drain()
+	log.Printf("Draining: readiness now reports not_ready")
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":  "draining",
+		"pending": len(s.rpcClient.Pending()),
+	})
+}
+
+func (s *Server) adminWebhooks(w http.ResponseWriter, r *http.Request) {
+	if s.webhooks == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"enabled": false})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"enabled":       true,
+		"subscriptions": s.webhooks.Subscriptions(),
+	})
+}
+
+func (s *Server) adminDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if s.webhooks == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"dead_letters": []any{}})
+		return
+	}
+
+	dead, err := s.webhooks.DeadLetters()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{
+			"status": "error",
+			"error":  err.Error(),
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"count":        len(dead),
+		"dead_letters": dead,
+	})
+}
+
+func (s *Server) adminRedeliver(w http.ResponseWriter, r *http.Request) {
+	if s.webhooks == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"status": "error",
+			"error":  "webhooks are disabled",
+		})
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if err := s.webhooks.Redeliver(id); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, webhook.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, map[string]string{
+			"status": "error",
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	log.Printf("[%s] Redelivering webhook %s", middleware.GetRequestID(r.Context()), id)
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"status": "redelivering",
+		"id":     id,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
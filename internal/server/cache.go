@@ -0,0 +1,44 @@
+package server
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jhaveripatric/agent-gateway/internal/manifest"
+	"github.com/jhaveripatric/agent-gateway/internal/router"
+	"github.com/jhaveripatric/agent-gateway/internal/rpc"
+)
+
+// syncCacheRules points each action's invalidate_on patterns at its cache
+// entries and subscribes to patterns not already bound. Patterns dropped
+// by a reload stay bound but no longer match any rule.
+func (s *Server) syncCacheRules(manifests []manifest.Manifest) error {
+	rules := make(map[string][]string)
+	for _, m := range manifests {
+		for _, a := range m.Actions {
+			for _, pattern := range a.Cache.InvalidateOn {
+				rules[pattern] = append(rules[pattern], router.CacheTag(m.Name, a.Name))
+			}
+		}
+	}
+	s.cache.SetRules(rules)
+
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	for pattern := range rules {
+		if s.cacheSubs[pattern] {
+			continue
+		}
+		err := s.rpcClient.Subscribe(pattern, func(routingKey string, _ *rpc.Response) {
+			if n := s.cache.InvalidateEvent(routingKey); n > 0 {
+				log.Printf("Cache: %s invalidated %d entries", routingKey, n)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("subscribe cache invalidation: %w", err)
+		}
+		s.cacheSubs[pattern] = true
+	}
+	return nil
+}
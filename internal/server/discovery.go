@@ -0,0 +1,72 @@
+package server
+
+import (
+	"log"
+	"path"
+
+	"github.com/jhaveripatric/agent-gateway/internal/manifest"
+)
+
+// discoverManifests appends manifests found under the discovery dirs and
+// globs, skipping files already loaded and names already taken.
+func (s *Server) discoverManifests(loader *manifest.Loader, loaded map[string]bool, manifests []manifest.Manifest) []manifest.Manifest {
+	d := s.cfg.Discovery
+	if len(d.ManifestDirs) == 0 && len(d.ManifestGlobs) == 0 {
+		return manifests
+	}
+
+	paths, err := loader.Discover(d.ManifestDirs, d.ManifestFile, d.ManifestGlobs)
+	if err != nil {
+		log.Printf("Warning: manifest discovery: %v", err)
+	}
+
+	names := make(map[string]bool, len(manifests))
+	for _, m := range manifests {
+		names[m.Name] = true
+	}
+
+	for _, p := range paths {
+		if loaded[p] {
+			continue
+		}
+		loaded[p] = true
+
+		m, err := loader.Load(p)
+		if err != nil {
+			log.Printf("Warning: failed to load discovered manifest: %v", err)
+			continue
+		}
+		if !discoverable(m.Name, d.Include, d.Exclude) {
+			continue
+		}
+		if names[m.Name] {
+			log.Printf("Warning: skipping %s: agent %s is already loaded", p, m.Name)
+			continue
+		}
+		names[m.Name] = true
+
+		log.Printf("Discovered manifest: %s v%s (%d actions) at %s",
+			m.Name, m.Version, len(m.Actions), p)
+		manifests = append(manifests, *m)
+	}
+	return manifests
+}
+
+// discoverable reports whether an agent name passes the include and
+// exclude patterns. Patterns were checked when the config was loaded.
+func discoverable(name string, include, exclude []string) bool {
+	for _, p := range exclude {
+		if ok, _ := path.Match(p, name); ok {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, p := range include {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"time"
+
+	"github.com/jhaveripatric/agent-gateway/internal/health"
+	"github.com/jhaveripatric/agent-gateway/internal/rpc"
+)
+
+// initHealth tracks agent liveness from heartbeat events. Queue probes
+// start with startProbes once manifests are loaded.
+func (s *Server) initHealth() error {
+	cfg := s.cfg.Health
+	s.health = health.NewTracker(cfg.HeartbeatTimeout)
+
+	for _, pattern := range cfg.HeartbeatEvents {
+		err := s.rpcClient.Subscribe(pattern, func(_ string, event *rpc.Response) {
+			if name := heartbeatAgent(event); name != "" {
+				s.health.Heartbeat(name, time.Now())
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("subscribe heartbeats: %w", err)
+		}
+	}
+	return nil
+}
+
+// heartbeatAgent names the agent behind a heartbeat: the "agent" data
+// field, or the last segment of the event source (e.g. /agents/rbac-agent).
+func heartbeatAgent(event *rpc.Response) string {
+	if name, _ := event.Data["agent"].(string); name != "" {
+		return name
+	}
+	if event.Event != nil && event.Event.Source != "" {
+		return path.Base(event.Event.Source)
+	}
+	return ""
+}
+
+// startProbes checks the consumer count of each manifest's queue every
+// probe interval.
+func (s *Server) startProbes() {
+	interval := s.cfg.Health.ProbeInterval
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		for {
+			s.probeAgents()
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func (s *Server) probeAgents() {
+	s.mu.RLock()
+	manifests := s.manifests
+	s.mu.RUnlock()
+
+	for _, m := range manifests {
+		if m.Queue == "" {
+			continue
+		}
+		consumers, err := s.rpcClient.QueueConsumers(m.Queue)
+		if errors.Is(err, rpc.ErrQueueNotFound) {
+			// Nobody declared the queue, so nobody consumes from it
+			consumers, err = 0, nil
+		}
+		if err != nil {
+			log.Printf("Warning: probe %s: %v", m.Name, err)
+		}
+		s.health.Probe(m.Name, consumers, err)
+	}
+}
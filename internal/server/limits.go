@@ -0,0 +1,30 @@
+package server
+
+import (
+	"github.com/jhaveripatric/agent-gateway/internal/config"
+	"github.com/jhaveripatric/agent-gateway/internal/limiter"
+)
+
+// newLimits builds the global and per-agent concurrency limiters.
+func newLimits(cfg config.LimitsConfig) *limiter.Set {
+	overrides := make(map[string]limiter.Config, len(cfg.Agents))
+	for name, a := range cfg.Agents {
+		overrides[name] = agentLimit(a)
+	}
+	return limiter.NewSet(
+		limiter.Config{MaxInFlight: cfg.MaxInFlight},
+		agentLimit(cfg.Agent),
+		overrides,
+	)
+}
+
+func agentLimit(a config.AgentLimitConfig) limiter.Config {
+	return limiter.Config{
+		MaxInFlight:   a.MaxInFlight,
+		QueueSize:     a.QueueSize,
+		QueueTimeout:  a.QueueTimeout,
+		Adaptive:      a.Adaptive == "aimd",
+		MinInFlight:   a.MinInFlight,
+		TargetLatency: a.TargetLatency,
+	}
+}
@@ -0,0 +1,307 @@
+package server
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jhaveripatric/agent-gateway/internal/health"
+	"github.com/jhaveripatric/agent-gateway/internal/manifest"
+	"github.com/jhaveripatric/agent-gateway/internal/rpc"
+)
+
+// signedMaxAge bounds how far a signed announcement or withdrawal may be
+// from the gateway's clock, so a captured one cannot be replayed later.
+const signedMaxAge = 5 * time.Minute
+
+// announcedAgent is an agent mounted from a bus announcement.
+type announcedAgent struct {
+	manifest manifest.Manifest
+	keyID    string
+	digest   [sha256.Size]byte
+}
+
+// registrationState is the newest signed message accepted for an agent. It
+// outlives the agent's mount, so replays of older announcements stay
+// rejected after a withdrawal.
+type registrationState struct {
+	version  uint64
+	digest   [sha256.Size]byte
+	signedAt int64
+}
+
+// registrationKey is an allowlisted announcement signing key.
+type registrationKey struct {
+	pub    crypto.PublicKey
+	agents []string
+}
+
+// initRegistration loads announcement keys and subscribes to manifest
+// announcements and withdrawals.
+func (s *Server) initRegistration() error {
+	cfg := s.cfg.Registration
+
+	s.regKeys = make(map[string]registrationKey, len(cfg.Keys))
+	for _, k := range cfg.Keys {
+		pub, err := manifest.LoadPublicKey(k.PublicKeyFile)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", k.ID, err)
+		}
+		s.regKeys[k.ID] = registrationKey{pub: pub, agents: k.Agents}
+	}
+
+	// Mounting rebinds queues, which must not happen on the consumer
+	// goroutine that delivers these events.
+	for _, pattern := range cfg.AnnounceEvents {
+		err := s.rpcClient.Subscribe(pattern, func(_ string, event *rpc.Response) {
+			go s.announce(event.Data)
+		})
+		if err != nil {
+			return fmt.Errorf("subscribe announcements: %w", err)
+		}
+	}
+	for _, pattern := range cfg.WithdrawEvents {
+		err := s.rpcClient.Subscribe(pattern, func(_ string, event *rpc.Response) {
+			go s.withdraw(event.Data)
+		})
+		if err != nil {
+			return fmt.Errorf("subscribe withdrawals: %w", err)
+		}
+	}
+
+	if s.health != nil && len(s.cfg.Health.HeartbeatEvents) > 0 {
+		go s.sweepLapsed(s.cfg.Health.HeartbeatTimeout / 2)
+	} else {
+		log.Printf("Warning: registration without heartbeat_events: announced agents stay mounted until withdrawn")
+	}
+
+	log.Printf("Registration enabled: %d keys", len(s.regKeys))
+	return nil
+}
+
+// announce verifies a signed manifest announcement and mounts its routes.
+// Announcements carry key_id, the manifest YAML, version (increasing with
+// every manifest change), timestamp (Unix seconds) and a base64 signature
+// of "announce:<version>:<timestamp>:<manifest>".
+func (s *Server) announce(data map[string]any) {
+	keyID, _ := data["key_id"].(string)
+	raw, _ := data["manifest"].(string)
+	version, _ := data["version"].(float64)
+	ts, _ := data["timestamp"].(float64)
+
+	signed := fmt.Sprintf("announce:%d:%d:%s", int64(version), int64(ts), raw)
+	key, err := s.verifyAnnouncement(keyID, []byte(signed), data["signature"])
+	if err != nil {
+		log.Printf("Warning: rejected manifest announcement: %v", err)
+		return
+	}
+	if version < 1 {
+		log.Printf("Warning: rejected manifest announcement from key %s: version is required", keyID)
+		return
+	}
+	if !fresh(int64(ts)) {
+		log.Printf("Warning: rejected stale manifest announcement from key %s", keyID)
+		return
+	}
+
+	m, err := manifest.ParseLiteral([]byte(raw))
+	if err != nil {
+		log.Printf("Warning: rejected manifest announcement from key %s: %v", keyID, err)
+		return
+	}
+	if !slices.Contains(key.agents, m.Name) {
+		log.Printf("Warning: key %s may not register agent %s", keyID, m.Name)
+		return
+	}
+	if m.JWT != nil {
+		// Key paths only make sense for manifests on the gateway's disk
+		log.Printf("Warning: ignoring jwt settings in announced manifest %s", m.Name)
+		m.JWT = nil
+	}
+	m.ManifestPath = "announced:" + keyID
+
+	s.mountMu.Lock()
+	defer s.mountMu.Unlock()
+
+	digest := sha256.Sum256([]byte(raw))
+	next := registrationState{version: uint64(version), digest: digest, signedAt: int64(ts)}
+	if st, ok := s.regState[m.Name]; ok {
+		switch {
+		case next.signedAt <= st.signedAt:
+			log.Printf("Warning: rejected replayed announcement of %s", m.Name)
+			return
+		case next.version < st.version:
+			log.Printf("Warning: rejected announcement of %s: version %d is older than %d", m.Name, next.version, st.version)
+			return
+		case next.version == st.version && next.digest != st.digest:
+			log.Printf("Warning: rejected announcement of %s: manifest changed without a version bump", m.Name)
+			return
+		}
+	}
+
+	if pinned(s.static, m.Name) {
+		log.Printf("Warning: agent %s is pinned by static config; announcement ignored", m.Name)
+		return
+	}
+	if conflict := routeConflict(s.static, s.announced, m); conflict != "" {
+		log.Printf("Warning: rejected manifest %s: route %s is already mounted", m.Name, conflict)
+		return
+	}
+
+	// An announcement is proof of life
+	if s.health != nil {
+		s.health.Heartbeat(m.Name, time.Now())
+	}
+
+	prev, existed := s.announced[m.Name]
+	if existed && prev.digest == digest {
+		s.regState[m.Name] = next
+		return
+	}
+
+	s.announced[m.Name] = announcedAgent{manifest: *m, keyID: keyID, digest: digest}
+	if err := s.mount(); err != nil {
+		log.Printf("Warning: mount %s: %v", m.Name, err)
+		if existed {
+			s.announced[m.Name] = prev
+		} else {
+			delete(s.announced, m.Name)
+		}
+		return
+	}
+	s.regState[m.Name] = next
+	log.Printf("Registered agent %s v%s (%d actions) with key %s",
+		m.Name, m.Version, len(m.Actions), keyID)
+}
+
+// withdraw unmounts an announced agent. Withdrawals carry key_id, agent,
+// timestamp (Unix seconds) and a base64 signature of
+// "withdraw:<agent>:<timestamp>".
+func (s *Server) withdraw(data map[string]any) {
+	keyID, _ := data["key_id"].(string)
+	name, _ := data["agent"].(string)
+	ts, _ := data["timestamp"].(float64)
+
+	signed := "withdraw:" + name + ":" + strconv.FormatInt(int64(ts), 10)
+	key, err := s.verifyAnnouncement(keyID, []byte(signed), data["signature"])
+	if err != nil {
+		log.Printf("Warning: rejected withdrawal of %s: %v", name, err)
+		return
+	}
+	if !slices.Contains(key.agents, name) {
+		log.Printf("Warning: key %s may not withdraw agent %s", keyID, name)
+		return
+	}
+	if !fresh(int64(ts)) {
+		log.Printf("Warning: rejected stale withdrawal of %s", name)
+		return
+	}
+
+	s.mountMu.Lock()
+	defer s.mountMu.Unlock()
+
+	// Announcements signed before the withdrawal stay rejected
+	st := s.regState[name]
+	if int64(ts) <= st.signedAt {
+		log.Printf("Warning: rejected replayed withdrawal of %s", name)
+		return
+	}
+	st.signedAt = int64(ts)
+	s.regState[name] = st
+
+	if _, ok := s.announced[name]; !ok {
+		return
+	}
+	delete(s.announced, name)
+	log.Printf("Unregistered agent %s: withdrawn", name)
+
+	if err := s.mount(); err != nil {
+		log.Printf("Warning: remount after unregistering: %v", err)
+	}
+}
+
+// fresh reports whether a signed Unix timestamp is within signedMaxAge of
+// now.
+func fresh(ts int64) bool {
+	age := time.Since(time.Unix(ts, 0))
+	return age <= signedMaxAge && age >= -signedMaxAge
+}
+
+// pinned reports whether a static manifest holds name.
+func pinned(static []manifest.Manifest, name string) bool {
+	for _, m := range static {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sweepLapsed unmounts announced agents whose heartbeats have lapsed.
+func (s *Server) sweepLapsed(interval time.Duration) {
+	for range time.Tick(interval) {
+		s.mountMu.Lock()
+		removed := 0
+		for name := range s.announced {
+			if s.health.Status(name) == health.StatusDown {
+				delete(s.announced, name)
+				log.Printf("Unregistered agent %s: heartbeat lapsed", name)
+				removed++
+			}
+		}
+		if removed > 0 {
+			if err := s.mount(); err != nil {
+				log.Printf("Warning: remount after unregistering: %v", err)
+			}
+		}
+		s.mountMu.Unlock()
+	}
+}
+
+func (s *Server) verifyAnnouncement(keyID string, data []byte, signature any) (registrationKey, error) {
+	key, ok := s.regKeys[keyID]
+	if !ok {
+		return registrationKey{}, fmt.Errorf("unknown key %q", keyID)
+	}
+	encoded, _ := signature.(string)
+	sig, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sig) == 0 {
+		return registrationKey{}, fmt.Errorf("missing or malformed signature")
+	}
+	if err := manifest.VerifySignature(key.pub, data, sig); err != nil {
+		return registrationKey{}, err
+	}
+	return key, nil
+}
+
+// routeConflict returns the first method and path of m already mounted by
+// another agent, or "".
+func routeConflict(static []manifest.Manifest, announced map[string]announcedAgent, m *manifest.Manifest) string {
+	taken := make(map[string]bool)
+	add := func(other manifest.Manifest) {
+		if other.Name == m.Name {
+			return
+		}
+		for _, a := range other.Actions {
+			taken[a.HTTP.Method+" "+a.HTTP.Path] = true
+		}
+	}
+	for _, other := range static {
+		add(other)
+	}
+	for _, other := range announced {
+		add(other.manifest)
+	}
+
+	for _, a := range m.Actions {
+		if route := a.HTTP.Method + " " + a.HTTP.Path; taken[route] {
+			return route
+		}
+	}
+	return ""
+}
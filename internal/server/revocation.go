@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jhaveripatric/agent-gateway/internal/auth"
+	"github.com/jhaveripatric/agent-gateway/internal/rpc"
+)
+
+// initRevocations subscribes to session revocation events and rebuilds
+// the deny-list from a session-agent snapshot.
+func (s *Server) initRevocations() error {
+	cfg := s.cfg.Auth.Revocation
+	s.revocations = auth.NewRevocationList()
+
+	for _, pattern := range cfg.Events {
+		err := s.rpcClient.Subscribe(pattern, func(_ string, event *rpc.Response) {
+			s.applyRevocation(event.Data)
+		})
+		if err != nil {
+			return fmt.Errorf("subscribe revocations: %w", err)
+		}
+	}
+
+	// Session-agent may not be up yet; start with an empty list rather
+	// than refusing to serve.
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.SnapshotTimeout)
+	defer cancel()
+
+	resp, err := s.rpcClient.Call(ctx, cfg.SnapshotEvent, map[string]any{}, rpc.CallOptions{
+		Timeout: cfg.SnapshotTimeout,
+	})
+	if err != nil {
+		log.Printf("Warning: revocation snapshot failed: %v", err)
+		return nil
+	}
+
+	entries, _ := resp.Data["revocations"].([]any)
+	for _, e := range entries {
+		if entry, ok := e.(map[string]any); ok {
+			s.applyRevocation(entry)
+		}
+	}
+
+	tokens, users := s.revocations.Len()
+	log.Printf("Loaded revocation snapshot: %d tokens, %d users", tokens, users)
+	return nil
+}
+
+// applyRevocation records a revocation entry. Entries carry a jti to revoke
+// one token, or only a user_id to revoke every token issued to that user
+// up to revoked_at.
+func (s *Server) applyRevocation(data map[string]any) {
+	jti, _ := data["jti"].(string)
+	userID, _ := data["user_id"].(string)
+
+	expires, ok := parseTime(data["expires_at"])
+	if !ok {
+		expires = time.Now().Add(s.cfg.Auth.Revocation.MaxTokenTTL)
+	}
+
+	switch {
+	case jti != "":
+		s.revocations.RevokeToken(jti, expires)
+	case userID != "":
+		revokedAt, ok := parseTime(data["revoked_at"])
+		if !ok {
+			revokedAt = time.Now()
+		}
+		s.revocations.RevokeUser(userID, revokedAt, expires)
+	default:
+		log.Printf("Warning: ignoring revocation without jti or user_id")
+	}
+}
+
+// parseTime accepts RFC 3339 strings and Unix seconds.
+func parseTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case string:
+		parsed, err := time.Parse(time.RFC3339, t)
+		return parsed, err == nil
+	case float64:
+		return time.Unix(int64(t), 0), true
+	}
+	return time.Time{}, false
+}
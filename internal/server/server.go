@@ -1,81 +1,256 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/netip"
 	"path/filepath"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/cors"
 	"github.com/jhaveripatric/agent-gateway/internal/auth"
+	"github.com/jhaveripatric/agent-gateway/internal/cache"
 	"github.com/jhaveripatric/agent-gateway/internal/config"
+	"github.com/jhaveripatric/agent-gateway/internal/health"
+	"github.com/jhaveripatric/agent-gateway/internal/idempotency"
+	"github.com/jhaveripatric/agent-gateway/internal/limiter"
 	"github.com/jhaveripatric/agent-gateway/internal/manifest"
 	"github.com/jhaveripatric/agent-gateway/internal/middleware"
 	"github.com/jhaveripatric/agent-gateway/internal/router"
 	"github.com/jhaveripatric/agent-gateway/internal/rpc"
+	"github.com/jhaveripatric/agent-gateway/internal/tenant"
+	"github.com/jhaveripatric/agent-gateway/internal/webhook"
 )
 
 // Server is the HTTP gateway server.
 type Server struct {
-	cfg         *config.Config
-	router      chi.Router
-	rpcClient   *rpc.Client
-	jwtVerifier *auth.JWTVerifier
-	manifests   []manifest.Manifest
+	cfg            *config.Config
+	rpcClient      *rpc.Client
+	jwtVerifier    *auth.JWTVerifier
+	verifiers      *auth.VerifierSet
+	apiKeys        *auth.APIKeyStore
+	session        *auth.SessionCookie
+	revocations    *auth.RevocationList
+	tenants        *tenant.Registry
+	webhooks       *webhook.Dispatcher
+	deliveries     *webhook.Deduper
+	idempotency    *idempotency.Guard
+	cache          *cache.Cache
+	coalesce       *cache.Group
+	limits         *limiter.Set
+	health         *health.Tracker
+	trustedProxies []netip.Prefix
+
+	mu        sync.RWMutex
+	router    chi.Router
+	manifests []manifest.Manifest
+	routes    []router.Route
+	draining  bool
+
+	cacheMu   sync.Mutex
+	cacheSubs map[string]bool // invalidation patterns already subscribed
+
+	// mountMu serialises router rebuilds from reloads and announcements.
+	mountMu   sync.Mutex
+	static    []manifest.Manifest // from config, pinned
+	announced map[string]announcedAgent
+	regState  map[string]registrationState
+	regKeys   map[string]registrationKey
 }
 
 // New creates a new gateway server.
 func New(cfg *config.Config) (*Server, error) {
-	s := &Server{cfg: cfg}
+	s := &Server{
+		cfg:        cfg,
+		deliveries: webhook.NewDeduper(),
+		cache:      cache.New(cfg.Cache.MaxEntries),
+		coalesce:   cache.NewGroup(),
+		cacheSubs:  make(map[string]bool),
+		announced:  make(map[string]announcedAgent),
+		regState:   make(map[string]registrationState),
+	}
+
+	trusted, err := middleware.ParseTrustedProxies(cfg.Gateway.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	s.trustedProxies = trusted
 
 	// Initialize RPC client
 	rpcClient, err := rpc.NewClient(rpc.Config{
 		URL:      cfg.Infrastructure.RabbitMQ.URL,
 		Exchange: cfg.Infrastructure.RabbitMQ.Exchange,
+		Source:   cfg.Infrastructure.RabbitMQ.EventSource,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("init rpc client: %w", err)
 	}
 	s.rpcClient = rpcClient
-	log.Printf("Connected to RabbitMQ at %s", cfg.Infrastructure.RabbitMQ.URL)
+	log.Printf("Connected to RabbitMQ at %s", config.RedactURL(cfg.Infrastructure.RabbitMQ.URL))
+
+	if cfg.Tenancy.Enabled {
+		if err := s.initTenancy(); err != nil {
+			return nil, fmt.Errorf("init tenancy: %w", err)
+		}
+	}
 
 	// Initialize JWT verifier
 	s.jwtVerifier = auth.NewJWTVerifier("agenteco", "agent-gateway")
+	s.verifiers = auth.NewVerifierSet(s.jwtVerifier)
+	s.initExternalAuth()
 
-	if err := s.loadManifests(); err != nil {
-		return nil, err
+	if cfg.Auth.APIKeys.File != "" {
+		s.apiKeys = auth.NewAPIKeyStore(cfg.Auth.APIKeys.Header, cfg.Auth.APIKeys.QueryParam)
+	}
+	if cfg.Auth.SessionCookie.Enabled {
+		s.session = newSessionCookie(cfg.Auth.SessionCookie)
+	}
+	if cfg.Auth.Revocation.Enabled {
+		if err := s.initRevocations(); err != nil {
+			return nil, err
+		}
 	}
 
-	// Load public keys from manifests
-	if err := s.loadJWTKeys(); err != nil {
+	if cfg.Limits.Enabled {
+		s.limits = newLimits(cfg.Limits)
+	}
+	if cfg.Health.Enabled() {
+		if err := s.initHealth(); err != nil {
+			return nil, fmt.Errorf("init health: %w", err)
+		}
+	}
+	if cfg.Idempotency.Enabled {
+		s.idempotency = idempotency.NewGuard(idempotency.NewMemoryStore())
+	}
+	if cfg.Webhooks.Enabled {
+		if err := s.initWebhooks(); err != nil {
+			return nil, fmt.Errorf("init webhooks: %w", err)
+		}
+	}
+
+	if err := s.Reload(); err != nil {
 		return nil, err
 	}
+	if s.health != nil {
+		s.startProbes()
+	}
+	if cfg.Registration.Enabled {
+		if err := s.initRegistration(); err != nil {
+			return nil, fmt.Errorf("init registration: %w", err)
+		}
+	}
 
 	return s, nil
 }
 
-func (s *Server) loadManifests() error {
-	loader := manifest.NewLoader(".")
+// Reload re-reads agent manifests and JWT keys and swaps in a freshly
+// built router. In-flight requests finish on the previous router.
+func (s *Server) Reload() error {
+	manifests, err := s.loadManifests()
+	if err != nil {
+		return err
+	}
 
+	// Load public keys from manifests
+	if err := s.loadJWTKeys(manifests); err != nil {
+		return err
+	}
+
+	if s.apiKeys != nil {
+		if err := s.apiKeys.Load(s.cfg.Auth.APIKeys.File); err != nil {
+			return fmt.Errorf("load api keys: %w", err)
+		}
+		log.Printf("Loaded API keys from %s", s.cfg.Auth.APIKeys.File)
+	}
+
+	s.mountMu.Lock()
+	defer s.mountMu.Unlock()
+
+	s.static = manifests
+	return s.mount()
+}
+
+// mount builds a router from the static manifests plus announced ones and
+// swaps it in. Called with s.mountMu held.
+func (s *Server) mount() error {
+	manifests := append([]manifest.Manifest(nil), s.static...)
+	names := make([]string, 0, len(s.announced))
+	for name := range s.announced {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// Static manifests stay the pinned baseline when a reload adds a
+		// name or route an announced agent holds
+		a := s.announced[name]
+		if pinned(s.static, name) {
+			delete(s.announced, name)
+			log.Printf("Unregistered agent %s: now pinned by static config", name)
+			continue
+		}
+		if route := routeConflict(s.static, nil, &a.manifest); route != "" {
+			delete(s.announced, name)
+			log.Printf("Unregistered agent %s: route %s is now mounted by static config", name, route)
+			continue
+		}
+		manifests = append(manifests, a.manifest)
+	}
+
+	if s.health != nil {
+		for _, m := range manifests {
+			s.health.Track(m.Name)
+		}
+	}
+
+	if err := s.syncCacheRules(manifests); err != nil {
+		return err
+	}
+
+	r, routes := s.buildRouter(manifests)
+
+	s.mu.Lock()
+	s.manifests = manifests
+	s.router = r
+	s.routes = routes
+	s.mu.Unlock()
+	return nil
+}
+
+// loadManifests loads the listed and discovered manifests. A listed
+// manifest that fails to load is an error, so a reload never drops an
+// agent's routes silently; discovered ones are skipped with a warning.
+func (s *Server) loadManifests() ([]manifest.Manifest, error) {
+	loader := manifest.NewLoader(s.cfg.Dir)
+	loaded := make(map[string]bool)
+
+	var manifests []manifest.Manifest
+	var errs []error
 	for _, agent := range s.cfg.Agents {
+		loaded[loader.Resolve(agent.ManifestPath)] = true
 		m, err := loader.Load(agent.ManifestPath)
 		if err != nil {
-			log.Printf("Warning: failed to load %s manifest: %v", agent.Name, err)
+			errs = append(errs, fmt.Errorf("load %s manifest: %w", agent.Name, err))
 			continue
 		}
 		log.Printf("Loaded manifest: %s v%s (%d actions)",
 			m.Name, m.Version, len(m.Actions))
-		s.manifests = append(s.manifests, *m)
+		manifests = append(manifests, *m)
 	}
 
-	s.router = s.buildRouter(s.manifests)
-	return nil
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return s.discoverManifests(loader, loaded, manifests), nil
 }
 
-func (s *Server) loadJWTKeys() error {
-	for _, m := range s.manifests {
+func (s *Server) loadJWTKeys(manifests []manifest.Manifest) error {
+	for _, m := range manifests {
 		if m.JWT != nil && m.JWT.PublicKeyPath != "" {
 			// Resolve path relative to manifest location
 			keyPath := m.JWT.PublicKeyPath
@@ -103,14 +278,18 @@ func (s *Server) loadJWTKeys() error {
 	return nil
 }
 
-func (s *Server) buildRouter(manifests []manifest.Manifest) chi.Router {
+func (s *Server) buildRouter(manifests []manifest.Manifest) (chi.Router, []router.Route) {
 	r := chi.NewRouter()
 
 	// Middleware stack (order matters)
 	r.Use(middleware.RequestID)
+	r.Use(middleware.RealIP(s.trustedProxies))
+	if s.tenants != nil {
+		r.Use(tenant.Middleware(s.tenants))
+	}
 	r.Use(middleware.Security)
 	r.Use(middleware.Recovery)
-	r.Use(cors.Handler(middleware.CORSOptions(s.cfg.Gateway.CORS.AllowedOrigins)))
+	r.Use(s.corsHandler())
 	r.Use(middleware.Logger)
 
 	// Health endpoints
@@ -118,11 +297,133 @@ func (s *Server) buildRouter(manifests []manifest.Manifest) chi.Router {
 	r.Get("/readyz", s.readyHandler)
 
 	// Mount agent routes with RPC and JWT
-	builder := router.NewBuilder(s.rpcClient, s.jwtVerifier)
+	builder := router.NewBuilder(s.rpcClient, s.verifiers, router.Config{
+		MaxBodyBytes:  s.cfg.Gateway.MaxBodyBytes,
+		MaxEventBytes: s.cfg.Gateway.MaxEventBytes,
+		APIKeys:       s.apiKeys,
+		Session:       s.session,
+		Revocations:   s.revocations,
+		Tenants:       s.tenants,
+		Deliveries:    s.deliveries,
+		Cache:         s.cache,
+		Coalesce:      s.coalesce,
+		Limits:        s.limits,
+		Health:        s.failFastHealth(),
+		RetryAfter:    s.cfg.Limits.RetryAfter,
+
+		Idempotency:       s.idempotency,
+		IdempotencyHeader: s.cfg.Idempotency.Header,
+		IdempotencyTTL:    s.cfg.Idempotency.TTL,
+	})
 	agentRoutes := builder.Build(manifests)
+
+	if s.cfg.Ingress.Enabled {
+		allow := make(map[string][]string, len(s.cfg.Ingress.Callers))
+		for _, c := range s.cfg.Ingress.Callers {
+			key := c.Method + ":" + c.ID
+			allow[key] = append(allow[key], c.Allow...)
+		}
+		agentRoutes.Post(s.cfg.Ingress.Path, builder.BuildIngress(router.IngressConfig{
+			Auth:         s.cfg.Ingress.Auth,
+			Timeout:      s.cfg.Ingress.Timeout,
+			MaxBodyBytes: s.cfg.Ingress.MaxBodyBytes,
+			Allow:        allow,
+		}))
+		log.Printf("Route: POST %s -> CloudEvents ingress (auth: %s)", s.cfg.Ingress.Path, s.cfg.Ingress.Auth)
+	}
+
 	r.Mount("/", agentRoutes)
 
-	return r
+	return r, builder.Routes()
+}
+
+// initExternalAuth registers configured OIDC issuers and introspection
+// endpoints. Unreachable issuers are retried on first use.
+func (s *Server) initExternalAuth() {
+	for _, o := range s.cfg.Auth.OIDC {
+		s.verifiers.AddIssuer(auth.NewOIDCProvider(auth.OIDCConfig{
+			Name:         o.Name,
+			Issuer:       o.Issuer,
+			Audience:     o.Audience,
+			DiscoveryURL: o.DiscoveryURL,
+			JWKSRefresh:  o.JWKSRefresh,
+			Claims:       claimsMapping(o.Claims),
+		}, nil))
+		log.Printf("Registered OIDC issuer: %s", o.Issuer)
+	}
+
+	for _, in := range s.cfg.Auth.Introspection {
+		s.verifiers.AddIntrospector(auth.NewIntrospector(auth.IntrospectionConfig{
+			Name:              in.Name,
+			Endpoint:          in.Endpoint,
+			ClientID:          in.ClientID,
+			ClientSecret:      in.ClientSecret,
+			CacheTTL:          in.CacheTTL,
+			CacheSize:         in.CacheSize,
+			InactiveCacheSize: in.InactiveCacheSize,
+			Claims:            claimsMapping(in.Claims),
+		}, nil))
+		log.Printf("Registered introspection endpoint: %s", config.RedactURL(in.Endpoint))
+	}
+
+	if len(s.cfg.Auth.OIDC) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.verifiers.Refresh(ctx); err != nil {
+			log.Printf("Warning: OIDC key prefetch failed: %v", err)
+		}
+	}
+}
+
+func claimsMapping(c config.ClaimsMapping) auth.ClaimsMapping {
+	return auth.ClaimsMapping{
+		UserID:   c.UserID,
+		Username: c.Username,
+		Roles:    c.Roles,
+	}
+}
+
+func newSessionCookie(cfg config.SessionCookieConfig) *auth.SessionCookie {
+	sameSite := http.SameSiteLaxMode
+	switch cfg.SameSite {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+
+	return &auth.SessionCookie{
+		Name:       cfg.Name,
+		CSRFName:   cfg.CSRFName,
+		CSRFHeader: cfg.CSRFHeader,
+		Domain:     cfg.Domain,
+		Path:       cfg.Path,
+		SameSite:   sameSite,
+		MaxAge:     cfg.MaxAge,
+		Secure:     !cfg.Insecure,
+	}
+}
+
+// ServeHTTP dispatches to the current router.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	h := s.router
+	s.mu.RUnlock()
+	h.ServeHTTP(w, r)
+}
+
+// Drain marks the server as draining so /readyz reports not ready and
+// load balancers stop sending new traffic.
+func (s *Server) Drain() {
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+}
+
+func (s *Server) isDraining() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.draining
 }
 
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
@@ -133,23 +434,76 @@ func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) readyHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if !s.rpcClient.Ready() {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status": "not_ready",
-			"reason": "rabbitmq disconnected",
-		})
-		return
+	resp := map[string]any{"status": "ready"}
+	status := http.StatusOK
+	if s.isDraining() {
+		resp = map[string]any{"status": "not_ready", "reason": "draining"}
+		status = http.StatusServiceUnavailable
+	} else if !s.rpcClient.Ready() {
+		resp = map[string]any{"status": "not_ready", "reason": "rabbitmq disconnected"}
+		status = http.StatusServiceUnavailable
+	}
+
+	// Agent health is informational: a dead agent fails its own routes,
+	// not the whole gateway.
+	if r.URL.Query().Has("verbose") && s.health != nil {
+		resp["agents"] = s.health.Snapshot()
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// failFastHealth returns the tracker for routes to consult, or nil when
+// fail_fast is off.
+func (s *Server) failFastHealth() *health.Tracker {
+	if !s.cfg.Health.FailFast {
+		return nil
+	}
+	return s.health
 }
 
 // Run starts the HTTP server.
 func (s *Server) Run() error {
-	addr := fmt.Sprintf(":%d", s.cfg.Gateway.Port)
-	log.Printf("Starting agent-gateway on %s", addr)
-	return http.ListenAndServe(addr, s.router)
+	if s.cfg.Admin.Addr != "" {
+		go func() {
+			log.Printf("Starting admin API on %s", s.cfg.Admin.Addr)
+			admin := &http.Server{
+				Addr:              s.cfg.Admin.Addr,
+				Handler:           s.adminRouter(),
+				ReadHeaderTimeout: s.cfg.Gateway.ReadHeaderTimeout,
+				IdleTimeout:       s.cfg.Gateway.IdleTimeout,
+			}
+			if err := admin.ListenAndServe(); err != nil {
+				log.Printf("Admin API error: %v", err)
+			}
+		}()
+	}
+
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", s.cfg.Gateway.Port),
+		Handler:           s,
+		ReadHeaderTimeout: s.cfg.Gateway.ReadHeaderTimeout,
+		ReadTimeout:       s.cfg.Gateway.ReadTimeout,
+		WriteTimeout:      s.cfg.Gateway.WriteTimeout,
+		IdleTimeout:       s.cfg.Gateway.IdleTimeout,
+		MaxHeaderBytes:    s.cfg.Gateway.MaxHeaderBytes,
+	}
+
+	if s.cfg.Gateway.TLS.Enabled() {
+		tlsCfg, err := buildTLSConfig(s.cfg.Gateway.TLS)
+		if err != nil {
+			return fmt.Errorf("init tls: %w", err)
+		}
+		srv.TLSConfig = tlsCfg
+
+		log.Printf("Starting agent-gateway on %s (TLS %s+, client auth: %s)",
+			srv.Addr, s.cfg.Gateway.TLS.MinVersion, s.cfg.Gateway.TLS.ClientAuth)
+		return srv.ListenAndServeTLS("", "")
+	}
+
+	log.Printf("Starting agent-gateway on %s", srv.Addr)
+	return srv.ListenAndServe()
 }
 
 // Close shuts down the server and connections.
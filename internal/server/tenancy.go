@@ -0,0 +1,93 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/cors"
+	"github.com/jhaveripatric/agent-gateway/internal/middleware"
+	"github.com/jhaveripatric/agent-gateway/internal/tenant"
+)
+
+// initTenancy builds the tenant registry and binds the reply queue to each
+// tenant's routing key prefix so sharded replies reach this gateway.
+func (s *Server) initTenancy() error {
+	cfg := s.cfg.Tenancy
+	reg := tenant.NewRegistry(tenant.Config{
+		Sources:    cfg.Sources,
+		Claim:      cfg.Claim,
+		Header:     cfg.Header,
+		BaseDomain: cfg.BaseDomain,
+		PathPrefix: cfg.PathPrefix,
+		Required:   cfg.Required,
+	})
+
+	base := s.rpcClient.Bindings()
+	for _, tc := range cfg.Tenants {
+		err := reg.Add(tenant.Tenant{
+			ID:               tc.ID,
+			CORSOrigins:      tc.CORSOrigins,
+			RoutingKeyPrefix: tc.RoutingKeyPrefix,
+		}, tc.RateLimit)
+		if err != nil {
+			return err
+		}
+
+		if tc.RoutingKeyPrefix == "" {
+			continue
+		}
+		for _, pattern := range base {
+			if err := s.rpcClient.Bind(tc.RoutingKeyPrefix + "." + pattern); err != nil {
+				return fmt.Errorf("tenant %s: %w", tc.ID, err)
+			}
+		}
+	}
+
+	s.tenants = reg
+	log.Printf("Tenancy enabled: %d tenants (sources: %v)", len(cfg.Tenants), cfg.Sources)
+	return nil
+}
+
+// corsHandler applies CORS with the gateway origins plus, for requests
+// resolved to a tenant, that tenant's origins.
+func (s *Server) corsHandler() func(http.Handler) http.Handler {
+	extra := []string{s.cfg.Auth.APIKeys.Header, s.cfg.Auth.SessionCookie.CSRFHeader}
+	if s.cfg.Idempotency.Enabled {
+		extra = append(extra, s.cfg.Idempotency.Header)
+	}
+	if s.cfg.Tenancy.Enabled {
+		extra = append(extra, s.cfg.Tenancy.Header)
+	}
+	origins := s.cfg.Gateway.CORS.AllowedOrigins
+	base := cors.New(middleware.CORSOptions(origins, extra...))
+
+	perTenant := make(map[string]*cors.Cors)
+	if s.tenants != nil {
+		for _, t := range s.tenants.Tenants() {
+			if len(t.CORSOrigins) == 0 {
+				continue
+			}
+			merged := append(append([]string(nil), origins...), t.CORSOrigins...)
+			perTenant[t.ID] = cors.New(middleware.CORSOptions(merged, extra...))
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		baseHandler := base.Handler(next)
+		tenantHandlers := make(map[string]http.Handler, len(perTenant))
+		for id, c := range perTenant {
+			tenantHandlers[id] = c.Handler(next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if t := tenant.GetTenant(r.Context()); t != nil {
+				if h, ok := tenantHandlers[t.ID]; ok {
+					h.ServeHTTP(w, r)
+					return
+				}
+			}
+			baseHandler.ServeHTTP(w, r)
+		})
+	}
+}
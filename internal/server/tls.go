@@ -0,0 +1,149 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jhaveripatric/agent-gateway/internal/config"
+)
+
+// certCheckInterval bounds how often certificate files are stat'ed.
+const certCheckInterval = 10 * time.Second
+
+// certReloader serves the listener certificate and reloads it when the
+// cert or key file changes on disk.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load key pair: %w", err)
+	}
+
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("stat %s: %w", path, err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	due := time.Since(r.lastCheck) >= certCheckInterval
+	if due {
+		r.lastCheck = time.Now()
+	}
+	r.mu.Unlock()
+
+	if due {
+		r.maybeReload()
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+func (r *certReloader) maybeReload() {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		log.Printf("Warning: certificate check failed: %v", err)
+		return
+	}
+
+	r.mu.RLock()
+	changed := modTime.After(r.modTime)
+	r.mu.RUnlock()
+	if !changed {
+		return
+	}
+
+	// Keep serving the previous certificate if the new pair is unusable,
+	// e.g. when the cert has been replaced but the key not yet.
+	if err := r.load(); err != nil {
+		log.Printf("Warning: certificate reload failed: %v", err)
+		return
+	}
+	log.Printf("Reloaded TLS certificate from %s", r.certFile)
+}
+
+// buildTLSConfig creates the listener TLS config with HTTP/2 via ALPN and
+// optional client certificate verification.
+func buildTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	reloader, err := newCertReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsCfg := &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	if cfg.MinVersion == "1.3" {
+		tlsCfg.MinVersion = tls.VersionTLS13
+	}
+
+	if cfg.ClientAuth == "none" || cfg.ClientAuth == "" {
+		return tlsCfg, nil
+	}
+
+	pem, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", cfg.ClientCAFile)
+	}
+	tlsCfg.ClientCAs = pool
+
+	if cfg.ClientAuth == "require" {
+		tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	} else {
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsCfg, nil
+}
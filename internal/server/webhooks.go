@@ -0,0 +1,48 @@
+package server
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jhaveripatric/agent-gateway/internal/webhook"
+)
+
+// initWebhooks starts outbound webhook delivery from a durable queue bound
+// to every subscription's routing key patterns.
+func (s *Server) initWebhooks() error {
+	cfg := s.cfg.Webhooks
+
+	store, err := webhook.NewFileStore(cfg.StoreDir)
+	if err != nil {
+		return err
+	}
+
+	subs := make([]webhook.Subscription, 0, len(cfg.Subscriptions))
+	for _, sub := range cfg.Subscriptions {
+		subs = append(subs, webhook.Subscription{
+			ID:             sub.ID,
+			Events:         sub.Events,
+			URL:            sub.URL,
+			Secret:         sub.Secret,
+			MaxAttempts:    sub.MaxAttempts,
+			InitialBackoff: sub.InitialBackoff,
+			MaxBackoff:     sub.MaxBackoff,
+			Timeout:        sub.Timeout,
+		})
+	}
+
+	s.webhooks = webhook.NewDispatcher(subs, store, nil, cfg.DedupeTTL)
+	if err := s.webhooks.Start(cfg.Workers); err != nil {
+		return err
+	}
+
+	patterns := s.webhooks.Patterns()
+	if len(patterns) == 0 {
+		return nil
+	}
+	if err := s.rpcClient.ConsumeQueue(cfg.Queue, patterns, s.webhooks.Handle); err != nil {
+		return fmt.Errorf("consume webhook queue: %w", err)
+	}
+	log.Printf("Delivering webhooks for %d subscriptions from queue %s", len(subs), cfg.Queue)
+	return nil
+}
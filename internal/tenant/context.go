@@ -0,0 +1,20 @@
+package tenant
+
+import (
+	"context"
+)
+
+type contextKey string
+
+const tenantKey contextKey = "tenant"
+
+// WithTenant adds the resolved tenant to context.
+func WithTenant(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey, t)
+}
+
+// GetTenant retrieves the resolved tenant from context.
+func GetTenant(ctx context.Context) *Tenant {
+	t, _ := ctx.Value(tenantKey).(*Tenant)
+	return t
+}
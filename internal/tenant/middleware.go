@@ -0,0 +1,52 @@
+package tenant
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/jhaveripatric/agent-gateway/internal/middleware"
+)
+
+// Middleware resolves the tenant from the header, subdomain or path,
+// rejects unknown tenants, strips the tenant path prefix and applies the
+// tenant's rate limit. Claim-based resolution happens after authentication.
+func Middleware(reg *Registry) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, path := reg.resolve(r)
+			if id == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			t, ok := reg.Get(id)
+			if !ok {
+				writeError(w, r, http.StatusNotFound, "unknown_tenant", "Unknown tenant")
+				return
+			}
+
+			if ok, retryAfter := t.Allow(); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				writeError(w, r, http.StatusTooManyRequests, "rate_limited", "Tenant rate limit exceeded")
+				return
+			}
+
+			if path != r.URL.Path {
+				r.URL.Path = path
+				r.URL.RawPath = ""
+			}
+			next.ServeHTTP(w, r.WithContext(WithTenant(r.Context(), t)))
+		})
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, errCode, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":      errCode,
+		"message":    message,
+		"request_id": middleware.GetRequestID(r.Context()),
+	})
+}
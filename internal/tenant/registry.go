@@ -0,0 +1,142 @@
+package tenant
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/jhaveripatric/agent-gateway/internal/middleware"
+)
+
+// Tenant is a registered customer tenant.
+type Tenant struct {
+	ID               string
+	CORSOrigins      []string
+	RoutingKeyPrefix string
+
+	limiter *middleware.RateLimiter
+}
+
+// Config controls how tenants are resolved from requests.
+type Config struct {
+	Sources    []string // ordered: "claim", "header", "subdomain", "path"
+	Claim      string
+	Header     string
+	BaseDomain string // subdomain source: <tenant>.<BaseDomain>
+	PathPrefix string // path source: <PathPrefix>/<tenant>/...
+	Required   bool
+}
+
+// Registry validates tenant IDs and resolves them from requests.
+type Registry struct {
+	cfg     Config
+	tenants map[string]*Tenant
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry(cfg Config) *Registry {
+	return &Registry{
+		cfg:     cfg,
+		tenants: make(map[string]*Tenant),
+	}
+}
+
+// Add registers a tenant. An empty rateLimit disables tenant limiting.
+func (reg *Registry) Add(t Tenant, rateLimit string) error {
+	if t.ID == "" {
+		return fmt.Errorf("tenant id is required")
+	}
+	if _, ok := reg.tenants[t.ID]; ok {
+		return fmt.Errorf("duplicate tenant %s", t.ID)
+	}
+	if rateLimit != "" {
+		rate, err := middleware.ParseRate(rateLimit)
+		if err != nil {
+			return fmt.Errorf("tenant %s: %w", t.ID, err)
+		}
+		t.limiter = middleware.NewRateLimiter(rate)
+	}
+	reg.tenants[t.ID] = &t
+	return nil
+}
+
+// Get returns a registered tenant.
+func (reg *Registry) Get(id string) (*Tenant, bool) {
+	t, ok := reg.tenants[id]
+	return t, ok
+}
+
+// Tenants returns all registered tenants.
+func (reg *Registry) Tenants() []*Tenant {
+	list := make([]*Tenant, 0, len(reg.tenants))
+	for _, t := range reg.tenants {
+		list = append(list, t)
+	}
+	return list
+}
+
+// Required reports whether every agent request needs a tenant.
+func (reg *Registry) Required() bool {
+	return reg.cfg.Required
+}
+
+// ClaimName returns the claim holding the tenant ID, or "" when claims are
+// not a configured source.
+func (reg *Registry) ClaimName() string {
+	for _, src := range reg.cfg.Sources {
+		if src == "claim" {
+			return reg.cfg.Claim
+		}
+	}
+	return ""
+}
+
+// Allow applies the tenant's rate limit.
+func (t *Tenant) Allow() (bool, int) {
+	if t.limiter == nil {
+		return true, 0
+	}
+	ok, wait := t.limiter.Allow(t.ID)
+	return ok, int(wait.Seconds()) + 1
+}
+
+// resolve finds the tenant ID from the request-level sources (everything
+// except claims, which are only known after authentication). For the path
+// source it also returns the path with the tenant prefix removed.
+func (reg *Registry) resolve(r *http.Request) (id, path string) {
+	path = r.URL.Path
+
+	for _, src := range reg.cfg.Sources {
+		switch src {
+		case "header":
+			if id = strings.TrimSpace(r.Header.Get(reg.cfg.Header)); id != "" {
+				return id, path
+			}
+
+		case "subdomain":
+			host := r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			suffix := "." + reg.cfg.BaseDomain
+			if reg.cfg.BaseDomain != "" && strings.HasSuffix(host, suffix) {
+				sub := strings.TrimSuffix(host, suffix)
+				if sub != "" && !strings.Contains(sub, ".") {
+					return sub, path
+				}
+			}
+
+		case "path":
+			prefix := strings.TrimSuffix(reg.cfg.PathPrefix, "/") + "/"
+			if rest, ok := strings.CutPrefix(r.URL.Path, prefix); ok {
+				id, tail, _ := strings.Cut(rest, "/")
+				if id != "" {
+					return id, "/" + tail
+				}
+			}
+		}
+	}
+
+	return "", path
+}
@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"sync"
+	"time"
+)
+
+// Deduper remembers recently seen delivery IDs so provider retries are
+// published once. Entries live in memory, per gateway instance.
+type Deduper struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	nextSweep time.Time
+}
+
+// NewDeduper creates an empty deduper.
+func NewDeduper() *Deduper {
+	return &Deduper{seen: make(map[string]time.Time)}
+}
+
+// Claim records key for ttl and reports whether it was not already seen.
+func (d *Deduper) Claim(key string, ttl time.Duration) bool {
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if now.After(d.nextSweep) {
+		for k, expires := range d.seen {
+			if now.After(expires) {
+				delete(d.seen, k)
+			}
+		}
+		d.nextSweep = now.Add(time.Minute)
+	}
+
+	if expires, ok := d.seen[key]; ok && now.Before(expires) {
+		return false
+	}
+	d.seen[key] = now.Add(ttl)
+	return true
+}
+
+// Forget releases a claimed key, e.g. when publishing failed and the
+// provider should be able to retry.
+func (d *Deduper) Forget(key string) {
+	d.mu.Lock()
+	delete(d.seen, key)
+	d.mu.Unlock()
+}
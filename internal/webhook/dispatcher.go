@@ -0,0 +1,299 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jhaveripatric/agent-gateway/internal/rpc"
+)
+
+// sweepInterval is how often expired delivered records are removed.
+const sweepInterval = 10 * time.Minute
+
+// Dispatcher delivers bus events to webhook subscriptions with retries.
+type Dispatcher struct {
+	subs   map[string]Subscription
+	order  []string
+	store  Store
+	client *http.Client
+	queue  chan *Delivery
+	retain time.Duration
+}
+
+// NewDispatcher creates a dispatcher. Delivered records are kept for
+// retain so events the bus delivers again are not sent twice. Call Start
+// to run its workers.
+func NewDispatcher(subs []Subscription, store Store, client *http.Client, retain time.Duration) *Dispatcher {
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	d := &Dispatcher{
+		subs:   make(map[string]Subscription, len(subs)),
+		store:  store,
+		client: client,
+		queue:  make(chan *Delivery, 1024),
+		retain: retain,
+	}
+	for _, s := range subs {
+		d.subs[s.ID] = s
+		d.order = append(d.order, s.ID)
+	}
+	return d
+}
+
+// Patterns returns the routing key patterns of all subscriptions.
+func (d *Dispatcher) Patterns() []string {
+	seen := make(map[string]bool)
+	var patterns []string
+	for _, id := range d.order {
+		for _, p := range d.subs[id].Events {
+			if !seen[p] {
+				seen[p] = true
+				patterns = append(patterns, p)
+			}
+		}
+	}
+	return patterns
+}
+
+// Subscriptions returns the configured subscriptions without secrets.
+func (d *Dispatcher) Subscriptions() []map[string]any {
+	list := make([]map[string]any, 0, len(d.order))
+	for _, id := range d.order {
+		s := d.subs[id]
+		list = append(list, map[string]any{
+			"id":           s.ID,
+			"events":       s.Events,
+			"url":          s.URL,
+			"max_attempts": s.MaxAttempts,
+		})
+	}
+	return list
+}
+
+// Start runs delivery workers and resumes pending deliveries from the store.
+func (d *Dispatcher) Start(workers int) error {
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+	go d.sweep()
+
+	pending, err := d.store.List(StatePending)
+	if err != nil {
+		return fmt.Errorf("resume deliveries: %w", err)
+	}
+	for _, del := range pending {
+		d.schedule(del)
+	}
+	if len(pending) > 0 {
+		log.Printf("Resumed %d pending webhook deliveries", len(pending))
+	}
+	return nil
+}
+
+// Handle persists a delivery for every subscription matching the routing
+// key. An error leaves the event unacknowledged on the bus.
+func (d *Dispatcher) Handle(routingKey string, event *rpc.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	for _, id := range d.order {
+		if !d.matches(d.subs[id], routingKey) {
+			continue
+		}
+
+		// The ID is derived from the event, so a redelivered event whose
+		// delivery is pending, dead or recently delivered is not sent twice.
+		del := &Delivery{
+			ID:             deliveryID(event.ID, id),
+			SubscriptionID: id,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Body:           body,
+			State:          StatePending,
+			NextAttempt:    time.Now(),
+			CreatedAt:      time.Now(),
+		}
+		if err := d.store.Create(del); err != nil {
+			if errors.Is(err, ErrExists) {
+				continue
+			}
+			return err
+		}
+		d.schedule(del)
+	}
+	return nil
+}
+
+// deliveryID identifies the delivery of an event to a subscription.
+func deliveryID(eventID, subID string) string {
+	h := sha256.Sum256([]byte(eventID + "\x00" + subID))
+	return hex.EncodeToString(h[:16])
+}
+
+// DeadLetters lists deliveries that exhausted their retries.
+func (d *Dispatcher) DeadLetters() ([]*Delivery, error) {
+	return d.store.List(StateDead)
+}
+
+// Redeliver moves a dead letter back to pending with a fresh retry budget.
+// The state changes in the store before scheduling, so concurrent calls
+// send it once.
+func (d *Dispatcher) Redeliver(id string) error {
+	del, err := d.store.Update(id, func(del *Delivery) error {
+		if del.State != StateDead {
+			return fmt.Errorf("delivery %s is %s, not dead", id, del.State)
+		}
+		del.State = StatePending
+		del.Attempts = 0
+		del.NextAttempt = time.Now()
+		del.LastError = ""
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	d.schedule(del)
+	return nil
+}
+
+// sweep removes delivered records older than the retention.
+func (d *Dispatcher) sweep() {
+	for {
+		delivered, err := d.store.List(StateDelivered)
+		if err != nil {
+			log.Printf("Warning: webhook sweep: %v", err)
+		}
+		for _, del := range delivered {
+			if time.Since(del.DeliveredAt) < d.retain {
+				continue
+			}
+			if err := d.store.Delete(del.ID); err != nil {
+				log.Printf("Webhook %s: %v", del.ID, err)
+			}
+		}
+		time.Sleep(sweepInterval)
+	}
+}
+
+func (d *Dispatcher) matches(s Subscription, routingKey string) bool {
+	for _, p := range s.Events {
+		if rpc.TopicMatch(p, routingKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// schedule queues a delivery at its next attempt time.
+func (d *Dispatcher) schedule(del *Delivery) {
+	wait := time.Until(del.NextAttempt)
+	if wait <= 0 {
+		go func() { d.queue <- del }()
+		return
+	}
+	time.AfterFunc(wait, func() { d.queue <- del })
+}
+
+func (d *Dispatcher) work() {
+	for del := range d.queue {
+		d.attempt(del)
+	}
+}
+
+func (d *Dispatcher) attempt(del *Delivery) {
+	sub, ok := d.subs[del.SubscriptionID]
+	if !ok {
+		// Subscription removed from config; park the delivery
+		del.State = StateDead
+		del.LastError = "subscription no longer configured"
+		d.save(del)
+		return
+	}
+
+	del.Attempts++
+	err := d.send(sub, del)
+	if err == nil {
+		del.State = StateDelivered
+		del.DeliveredAt = time.Now()
+		del.Body = nil
+		del.LastError = ""
+		d.save(del)
+		return
+	}
+
+	del.LastError = err.Error()
+	if del.Attempts >= sub.MaxAttempts {
+		del.State = StateDead
+		log.Printf("Webhook %s to %s dead-lettered after %d attempts: %v",
+			del.ID, sub.ID, del.Attempts, err)
+		d.save(del)
+		return
+	}
+
+	del.NextAttempt = time.Now().Add(backoff(sub, del.Attempts))
+	d.save(del)
+	d.schedule(del)
+}
+
+func (d *Dispatcher) send(sub Subscription, del *Delivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sub.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(del.Body))
+	if err != nil {
+		return err
+	}
+
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/cloudevents+json")
+	req.Header.Set(HeaderID, del.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, del.ID, ts, del.Body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (d *Dispatcher) save(del *Delivery) {
+	if err := d.store.Save(del); err != nil {
+		log.Printf("Webhook %s: %v", del.ID, err)
+	}
+}
+
+// backoff doubles from InitialBackoff up to MaxBackoff, with full jitter on
+// the upper half to spread retries.
+func backoff(sub Subscription, attempts int) time.Duration {
+	wait := sub.InitialBackoff
+	for i := 1; i < attempts && wait < sub.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > sub.MaxBackoff {
+		wait = sub.MaxBackoff
+	}
+	half := wait / 2
+	return half + time.Duration(rand.Int64N(int64(half)+1))
+}
@@ -0,0 +1,184 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Errors returned by stores.
+var (
+	ErrNotFound = errors.New("delivery not found")
+	ErrExists   = errors.New("delivery already exists")
+)
+
+// Store persists deliveries so retries and dead letters survive restarts.
+type Store interface {
+	Save(d *Delivery) error
+	// Create saves a new delivery, or returns ErrExists.
+	Create(d *Delivery) error
+	// Update applies fn to a stored delivery and saves it, atomically with
+	// other updates. An error from fn leaves the delivery unchanged.
+	Update(id string, fn func(d *Delivery) error) (*Delivery, error)
+	Get(id string) (*Delivery, error)
+	Delete(id string) error
+	List(state State) ([]*Delivery, error)
+}
+
+// FileStore keeps each delivery as a JSON file in a directory.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore creates the directory if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create webhook store: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save writes a delivery atomically.
+func (s *FileStore) Save(d *Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(d)
+}
+
+// Create writes a delivery unless one with its ID exists.
+func (s *FileStore) Create(d *Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.path(d.ID)); err == nil {
+		return ErrExists
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("read delivery: %w", err)
+	}
+	return s.write(d)
+}
+
+// Update reads a delivery, applies fn and writes it back.
+func (s *FileStore) Update(id string, fn func(d *Delivery) error) (*Delivery, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, err := s.read(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(d); err != nil {
+		return nil, err
+	}
+	if err := s.write(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *FileStore) write(d *Delivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("marshal delivery: %w", err)
+	}
+
+	tmp := s.path(d.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write delivery: %w", err)
+	}
+	if err := os.Rename(tmp, s.path(d.ID)); err != nil {
+		return fmt.Errorf("write delivery: %w", err)
+	}
+	return nil
+}
+
+// Get reads a delivery by ID.
+func (s *FileStore) Get(id string) (*Delivery, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read(id)
+}
+
+func (s *FileStore) read(id string) (*Delivery, error) {
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read delivery: %w", err)
+	}
+
+	var d Delivery
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("parse delivery %s: %w", id, err)
+	}
+	return &d, nil
+}
+
+// Delete removes a delivery. Deleting a missing delivery is not an error.
+func (s *FileStore) Delete(id string) error {
+	if !validID(id) {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delete delivery: %w", err)
+	}
+	return nil
+}
+
+// List returns deliveries in the given state, oldest first.
+func (s *FileStore) List(state State) ([]*Delivery, error) {
+	s.mu.Lock()
+	entries, err := os.ReadDir(s.dir)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("list deliveries: %w", err)
+	}
+
+	var list []*Delivery
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+		d, err := s.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		if d.State == state {
+			list = append(list, d)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list, nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// validID guards against path traversal through admin-supplied IDs.
+func validID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\.`)
+}
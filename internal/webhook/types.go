@@ -0,0 +1,42 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Subscription delivers events matching routing key patterns to a URL.
+type Subscription struct {
+	ID             string
+	Events         []string // routing key patterns, e.g. "audit.#"
+	URL            string
+	Secret         string
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Timeout        time.Duration
+}
+
+// State is a delivery's lifecycle state.
+type State string
+
+const (
+	StatePending   State = "pending"
+	StateDead      State = "dead"
+	StateDelivered State = "delivered" // kept without a body to dedupe redelivered events
+)
+
+// Delivery is one event on its way to one subscription.
+type Delivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Body           json.RawMessage `json:"body"` // structured CloudEvent
+	State          State           `json:"state"`
+	Attempts       int             `json:"attempts"`
+	NextAttempt    time.Time       `json:"next_attempt"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    time.Time       `json:"delivered_at"`
+}
@@ -0,0 +1,287 @@
+// Package yamlcheck decodes YAML documents strictly and reports every
+// problem found, each with the line it was found on.
+package yamlcheck
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Issue is one problem in a document.
+type Issue struct {
+	Line int    // 0 when the key is not in the document
+	Path string // key path, e.g. gateway.cors.allowed_origins[0]
+	Msg  string
+}
+
+func (i Issue) String() string {
+	s := i.Msg
+	if i.Path != "" {
+		s = i.Path + ": " + s
+	}
+	if i.Line > 0 {
+		s = fmt.Sprintf("line %d: %s", i.Line, s)
+	}
+	return s
+}
+
+// Error aggregates the issues found in a document.
+type Error struct {
+	Issues []Issue
+}
+
+func (e *Error) Error() string {
+	if len(e.Issues) == 1 {
+		return e.Issues[0].String()
+	}
+	lines := make([]string, 0, len(e.Issues)+1)
+	lines = append(lines, fmt.Sprintf("%d problems:", len(e.Issues)))
+	for _, i := range e.Issues {
+		lines = append(lines, "  "+i.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Report collects issues against a parsed document.
+type Report struct {
+	doc    *yaml.Node
+	issues []Issue
+}
+
+// NewReport starts a report for doc, which may be nil.
+func NewReport(doc *yaml.Node) *Report {
+	return &Report{doc: doc}
+}
+
+// Add records err against the key at path, e.g. "agents[1].name". An
+// empty path reports against the document as a whole.
+func (r *Report) Add(path string, err error) {
+	r.issues = append(r.issues, Issue{Line: r.line(path), Path: path, Msg: err.Error()})
+}
+
+// Addf records a formatted message against the key at path.
+func (r *Report) Addf(path, format string, args ...any) {
+	r.Add(path, fmt.Errorf(format, args...))
+}
+
+// Err returns the collected issues ordered by line, or nil if there are
+// none.
+func (r *Report) Err() error {
+	if len(r.issues) == 0 {
+		return nil
+	}
+	issues := append([]Issue(nil), r.issues...)
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Line < issues[j].Line })
+	return &Error{Issues: issues}
+}
+
+// typeErrLine matches the position prefix yaml.v3 puts on type errors.
+var typeErrLine = regexp.MustCompile(`^line (\d+): `)
+
+// Decode decodes doc into out, reporting keys that match no field as well
+// as values of the wrong type. Node.Decode has no KnownFields option, so
+// unknown keys are found by walking the document against out's type.
+func (r *Report) Decode(doc *yaml.Node, out any) {
+	r.unknown(doc, reflect.TypeOf(out), "")
+
+	err := doc.Decode(out)
+	if err == nil {
+		return
+	}
+	te, ok := err.(*yaml.TypeError)
+	if !ok {
+		r.Add("", err)
+		return
+	}
+	for _, msg := range te.Errors {
+		issue := Issue{Msg: msg}
+		if m := typeErrLine.FindStringSubmatch(msg); m != nil {
+			issue.Line, _ = strconv.Atoi(m[1])
+			issue.Msg = msg[len(m[0]):]
+		}
+		r.issues = append(r.issues, issue)
+	}
+}
+
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+func (r *Report) unknown(n *yaml.Node, t reflect.Type, path string) {
+	if n == nil {
+		return
+	}
+	if n.Kind == yaml.DocumentNode || n.Kind == yaml.AliasNode {
+		for _, c := range n.Content {
+			r.unknown(c, t, path)
+		}
+		if n.Kind == yaml.AliasNode {
+			r.unknown(n.Alias, t, path)
+		}
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		fields := fieldsOf(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			if isMerge(key) {
+				r.merged(val, t, path)
+				continue
+			}
+			ft, ok := fields[key.Value]
+			if !ok {
+				r.issues = append(r.issues, Issue{
+					Line: key.Line,
+					Path: join(path, key.Value),
+					Msg:  "unknown field",
+				})
+				continue
+			}
+			r.unknown(val, ft, join(path, key.Value))
+		}
+	case reflect.Slice, reflect.Array:
+		if n.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, c := range n.Content {
+			r.unknown(c, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if isMerge(n.Content[i]) {
+				r.merged(n.Content[i+1], t, path)
+				continue
+			}
+			r.unknown(n.Content[i+1], t.Elem(), join(path, n.Content[i].Value))
+		}
+	}
+}
+
+// isMerge reports whether key is a YAML merge key (<<).
+func isMerge(key *yaml.Node) bool {
+	return key.Kind == yaml.ScalarNode && key.ShortTag() == "!!merge"
+}
+
+// merged checks the mappings merged into a mapping of type t, given as
+// one mapping or alias or a sequence of them.
+func (r *Report) merged(n *yaml.Node, t reflect.Type, path string) {
+	if n.Kind == yaml.SequenceNode {
+		for _, c := range n.Content {
+			r.unknown(c, t, path)
+		}
+		return
+	}
+	r.unknown(n, t, path)
+}
+
+// fieldsOf maps the yaml keys of a struct, including inlined ones, to
+// their field types.
+func fieldsOf(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("yaml")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "inline") {
+			for k, v := range fieldsOf(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// line returns the line of the key at path, or of its nearest ancestor
+// present in the document.
+func (r *Report) line(path string) int {
+	n := r.doc
+	if n == nil {
+		return 0
+	}
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	line := 0
+	for _, seg := range segments(path) {
+		next, keyLine := child(n, seg)
+		if next == nil {
+			break
+		}
+		n, line = next, keyLine
+	}
+	return line
+}
+
+// child returns the value under seg, a key or a [index], and the line
+// where it starts.
+func child(n *yaml.Node, seg string) (*yaml.Node, int) {
+	if idx, ok := strings.CutPrefix(seg, "["); ok {
+		i, err := strconv.Atoi(strings.TrimSuffix(idx, "]"))
+		if err != nil || n.Kind != yaml.SequenceNode || i < 0 || i >= len(n.Content) {
+			return nil, 0
+		}
+		return n.Content[i], n.Content[i].Line
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil, 0
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == seg {
+			return n.Content[i+1], n.Content[i].Line
+		}
+	}
+	return nil, 0
+}
+
+// segments splits "a.b[2].c" into "a", "b", "[2]", "c".
+func segments(path string) []string {
+	var segs []string
+	for _, part := range strings.Split(path, ".") {
+		for part != "" {
+			i := strings.IndexByte(part[1:], '[')
+			if i < 0 {
+				segs = append(segs, part)
+				break
+			}
+			segs = append(segs, part[:i+1])
+			part = part[i+1:]
+		}
+	}
+	return segs
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}